@@ -0,0 +1,368 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"journal/models"
+	"journal/pkg/journal"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// completeCommand is the hidden command the completion scripts call back into
+// to fetch dynamic candidates such as entry IDs and tags.
+const completeCommand = "__complete"
+
+// command describes a top-level CLI command for the completion scripts.
+type command struct {
+	Name        string
+	Description string
+	TakesID     bool // Whether the first argument is an entry ID
+	TakesTag    bool // Whether the first argument is a tag
+}
+
+// commands lists every top-level command offered by the completion scripts.
+var commands = []command{
+	{Name: "create", Description: "Create a new entry"},
+	{Name: "list", Description: "List all entries, or those with a tag", TakesTag: true},
+	{Name: "get", Description: "Show a single entry", TakesID: true},
+	{Name: "update", Description: "Update the title and content of an entry", TakesID: true},
+	{Name: "delete", Description: "Delete an entry", TakesID: true},
+	{Name: "move", Description: "Move an entry to another notebook", TakesID: true},
+	{Name: "notebook", Description: "Create, list, rename or delete notebooks"},
+	{Name: "export", Description: "Write every entry as newline-delimited JSON"},
+	{Name: "interactive", Description: "Start the interactive prompt"},
+	{Name: "token", Description: "Create, list or revoke API tokens"},
+	{Name: "webhooks", Description: "Add, list, test or delete webhooks"},
+	{Name: "unlock", Description: "Unlock the encrypted journal, setting up encryption the first time"},
+	{Name: "lock", Description: "Forget the unlocked key"},
+	{Name: "passphrase", Description: "Change the encryption passphrase"},
+	{Name: "rotate", Description: "Re-encrypt every entry with a new key"},
+	{Name: "completion", Description: "Print a shell completion script"},
+}
+
+// globalFlag describes a flag that goes before the command, for the
+// completion scripts.
+type globalFlag struct {
+	Name        string
+	Description string
+	Value       string // What the flag's value is, shown by zsh
+}
+
+// globalFlags lists the flags main parses before the command. The completion
+// scripts skip them to find the command and pass them on to __complete, so
+// IDs are completed from the same journal the command will use.
+var globalFlags = []globalFlag{
+	{Name: "notebook", Description: "Only work with the entries of the named notebook", Value: "notebook"},
+	{Name: "remote", Description: "URL of a journal server to use instead of the local database", Value: "url"},
+	{Name: "token", Description: "API token for the remote journal", Value: "token"},
+	{Name: "timeout", Description: "How long a request to the remote journal may take", Value: "duration"},
+}
+
+// shells maps each supported shell to its completion script template.
+var shells = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Funcs(completionFuncs).Parse(bashCompletion)),
+	"zsh":  template.Must(template.New("zsh").Funcs(completionFuncs).Parse(zshCompletion)),
+	"fish": template.Must(template.New("fish").Funcs(completionFuncs).Parse(fishCompletion)),
+}
+
+var completionFuncs = template.FuncMap{
+	"names": commandNames,
+	"idCommands": func(sep string) string {
+		return commandsWhere(func(c command) bool { return c.TakesID }, sep)
+	},
+	"tagCommands": func(sep string) string {
+		return commandsWhere(func(c command) bool { return c.TakesTag }, sep)
+	},
+	// flags lists the global flags as they are offered
+	"flags": func(sep string) string {
+		var names []string
+		for _, f := range globalFlags {
+			names = append(names, "--"+f.Name)
+		}
+		return strings.Join(names, sep)
+	},
+	// flagForms lists every way of spelling the global flags, since the flag
+	// package accepts both one and two dashes
+	"flagForms": func(sep string) string {
+		var forms []string
+		for _, f := range globalFlags {
+			forms = append(forms, "-"+f.Name, "--"+f.Name)
+		}
+		return strings.Join(forms, sep)
+	},
+}
+
+// writeCompletion writes the completion script for the given shell to w.
+func writeCompletion(w io.Writer, shell string) error {
+	tmpl, ok := shells[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q (expected bash, zsh or fish)", shell)
+	}
+	return tmpl.Execute(w, struct {
+		Commands []command
+		Flags    []globalFlag
+		Complete string
+		Shells   string
+	}{commands, globalFlags, completeCommand, "bash zsh fish"})
+}
+
+// complete prints completion candidates for the requested kind, one per line.
+// Entry IDs are printed as "ID<TAB>Title" and tags as "tag<TAB>N entries" so
+// shells that support descriptions can show them next to the candidate.
+func complete(w io.Writer, journalInstance *journal.Journal, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: journal %s [ids|tags]", completeCommand)
+	}
+	switch args[0] {
+	case "ids":
+		entries, err := journalInstance.ListEntries()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			title := strings.Join(strings.Fields(entry.Title), " ")
+			fmt.Fprintf(w, "%s\t%s\n", entry.ID, title)
+		}
+		return nil
+	case "tags":
+		counts := map[string]int{}
+		err := journalInstance.ForEachEntry(func(entry models.Entry) error {
+			for _, tag := range journal.Tags(entry) {
+				counts[tag]++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, tag := range slices.Sorted(maps.Keys(counts)) {
+			noun := "entries"
+			if counts[tag] == 1 {
+				noun = "entry"
+			}
+			fmt.Fprintf(w, "%s\t%d %s\n", tag, counts[tag], noun)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown completion kind %q", args[0])
+	}
+}
+
+// commandsWhere lists the names of the commands matching the condition
+func commandsWhere(matches func(command) bool, sep string) string {
+	var names []string
+	for _, c := range commands {
+		if matches(c) {
+			names = append(names, c.Name)
+		}
+	}
+	return strings.Join(names, sep)
+}
+
+func commandNames() string {
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.Name
+	}
+	return strings.Join(names, " ")
+}
+
+const bashCompletion = `# bash completion for journal
+#
+# Load it in the current shell with:
+#   source <(journal completion bash)
+
+# _journal_words sets words to the words before the cursor, joining the
+# pieces bash splits at = and : so --remote=http://host:8080 stays one word.
+_journal_words() {
+    local line="${COMP_LINE:0:COMP_POINT}" rest word i
+    words=()
+    for ((i = 0; i <= COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        rest="${line#"${line%%[![:space:]]*}"}"
+        if [[ $i -gt 0 && ${#rest} -eq ${#line} ]]; then
+            words[${#words[@]}-1]+="$word"
+        else
+            words+=("$word")
+        fi
+        line="${rest:${#word}}"
+    done
+}
+
+_journal() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" value ids tags i command=0
+    local -a words globals
+    _journal_words
+    local last=$((${#words[@]} - 1))
+
+    # The command is the first word that isn't a global flag or its value
+    for ((i = 1; i < last; i++)); do
+        case "${words[i]}" in
+            {{ flagForms "|" }})
+                value="${words[i+1]}"
+                value="${value#[\"\']}"
+                globals+=("${words[i]}" "${value%[\"\']}")
+                ((i++))
+                ;;
+            -*)
+                globals+=("${words[i]}")
+                ;;
+            *)
+                command=$i
+                break
+                ;;
+        esac
+    done
+
+    if [[ $command -eq 0 ]]; then
+        # Nothing to offer for the value of a global flag
+        [[ $i -gt $last ]] && return
+        if [[ $cur == -* ]]; then
+            COMPREPLY=($(compgen -W "{{ flags " " }}" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W "{{ names }}" -- "$cur"))
+        fi
+        return
+    fi
+    [[ $last -eq $((command + 1)) ]] || return
+
+    case "${words[command]}" in
+        {{ idCommands "|" }})
+            ids=$("${words[0]}" "${globals[@]}" {{ .Complete }} ids 2>/dev/null | cut -f1)
+            COMPREPLY=($(compgen -W "$ids" -- "$cur"))
+            ;;
+        {{ tagCommands "|" }})
+            tags=$("${words[0]}" "${globals[@]}" {{ .Complete }} tags 2>/dev/null | cut -f1)
+            COMPREPLY=($(compgen -W "$tags" -- "$cur"))
+            ;;
+        completion)
+            COMPREPLY=($(compgen -W "{{ .Shells }}" -- "$cur"))
+            ;;
+    esac
+}
+complete -F _journal journal
+`
+
+const zshCompletion = `#compdef journal
+#
+# Load it in the current shell with:
+#   source <(journal completion zsh)
+# or save it as _journal somewhere in your $fpath.
+_journal() {
+    local curcontext="$curcontext" state line candidate flag journal=$words[1]
+    local -a commands ids tags globals
+    typeset -A opt_args
+    commands=({{ range .Commands }}
+        '{{ .Name }}:{{ .Description }}'{{ end }}
+    )
+
+    # Global flags only go before the command
+    _arguments -C -A '-*'{{ range .Flags }} \
+        '--{{ .Name }}=[{{ .Description }}]:{{ .Value }}: '{{ end }} \
+        '1: :->command' \
+        '*:: :->args'
+
+    case $state in
+        command)
+            _describe -t commands 'journal command' commands
+            ;;
+        args)
+            # Complete IDs and tags from the journal the command will use
+            for flag in ${(k)opt_args}; do
+                globals+=("$flag=${(Q)opt_args[$flag]}")
+            done
+            case $words[1] in
+                {{ idCommands "|" }})
+                    if (( CURRENT == 2 )); then
+                        for candidate in ${(f)"$($journal $globals {{ .Complete }} ids 2>/dev/null)"}; do
+                            ids+=("${candidate%%$'\t'*}:${${candidate#*$'\t'}//:/\\:}")
+                        done
+                        _describe -t ids 'entry id' ids
+                    fi
+                    ;;
+                {{ tagCommands "|" }})
+                    if (( CURRENT == 2 )); then
+                        for candidate in ${(f)"$($journal $globals {{ .Complete }} tags 2>/dev/null)"}; do
+                            tags+=("${candidate%%$'\t'*}:${candidate#*$'\t'}")
+                        done
+                        _describe -t tags 'tag' tags
+                    fi
+                    ;;
+                completion)
+                    (( CURRENT == 2 )) && _values 'shell' {{ .Shells }}
+                    ;;
+            esac
+            ;;
+    esac
+}
+
+if [ "$funcstack[1]" = "_journal" ]; then
+    _journal "$@"
+else
+    compdef _journal journal
+fi
+`
+
+const fishCompletion = `# fish completion for journal
+#
+# Load it in the current shell with:
+#   journal completion fish | source
+
+# __journal_tokens prints the global flags typed before the command with
+# "globals", or the command and its arguments with "args", failing if there
+# is no command yet.
+function __journal_tokens --argument-names part
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l globals
+    while set -q tokens[1]
+        switch $tokens[1]
+            case {{ flagForms " " }}
+                set -a globals $tokens[1..2]
+                set -e tokens[1]
+            case '-*'
+                set -a globals $tokens[1]
+            case '*'
+                break
+        end
+        set -e tokens[1]
+    end
+    if test "$part" = globals
+        printf '%s\n' $globals
+    else
+        set -q tokens[1]; and printf '%s\n' $tokens
+    end
+end
+
+function __journal_needs_command
+    __journal_tokens args >/dev/null; and return 1
+    # Nothing to offer for the value of a global flag
+    set -l globals (__journal_tokens globals)
+    not contains -- "$globals[-1]" {{ flagForms " " }}
+end
+
+# __journal_first_argument_of succeeds when the first argument of one of the
+# given commands is being completed
+function __journal_first_argument_of
+    set -l args (__journal_tokens args)
+    test (count $args) -eq 1; and contains -- $args[1] $argv
+end
+
+function __journal_ids
+    journal (__journal_tokens globals) {{ .Complete }} ids 2>/dev/null
+end
+
+function __journal_tags
+    journal (__journal_tokens globals) {{ .Complete }} tags 2>/dev/null
+end
+
+complete -c journal -f
+{{ range .Flags }}complete -c journal -n __journal_needs_command -l {{ .Name }} -r -d '{{ .Description }}'
+{{ end }}{{ range .Commands }}complete -c journal -n __journal_needs_command -a {{ .Name }} -d '{{ .Description }}'
+{{ end }}complete -c journal -n '__journal_first_argument_of {{ idCommands " " }}' -a '(__journal_ids)'
+complete -c journal -n '__journal_first_argument_of {{ tagCommands " " }}' -a '(__journal_tags)'
+complete -c journal -n '__journal_first_argument_of completion' -a '{{ .Shells }}'
+`
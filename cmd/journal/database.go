@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"journal/pkg/storage"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables configuring the local SQLite database
+const (
+	walEnv         = "JOURNAL_SQLITE_WAL"
+	busyTimeoutEnv = "JOURNAL_SQLITE_BUSY_TIMEOUT"
+)
+
+// sqliteOptions returns the default SQLite options with the changes set in
+// $JOURNAL_SQLITE_WAL and $JOURNAL_SQLITE_BUSY_TIMEOUT
+func sqliteOptions() storage.SQLiteOptions {
+	options := storage.DefaultSQLiteOptions()
+	if value := os.Getenv(walEnv); value != "" {
+		wal, err := strconv.ParseBool(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ignoring invalid %s %q: %v\n", walEnv, value, err)
+		} else {
+			options.WAL = wal
+		}
+	}
+	if value := os.Getenv(busyTimeoutEnv); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ignoring invalid %s %q: %v\n", busyTimeoutEnv, value, err)
+		} else {
+			options.BusyTimeout = timeout
+		}
+	}
+	return options
+}
+
+// serverStore connects to the MongoDB database the web server keeps accounts
+// and webhooks in, found like the server does from $MONGODB_URI or a .env
+// file, so the commands managing them change what the server sees.
+func serverStore() (*storage.MongoDBStorage, error) {
+	store, err := storage.NewMongoDBStorage("journal", "entries")
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the web server's database: %w", err)
+	}
+	return store, nil
+}
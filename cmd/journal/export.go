@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"journal/pkg/journal"
+	"os"
+)
+
+// runExport writes every entry as newline-delimited JSON, in the same format
+// as the server's export endpoint, to the named file or to stdout.
+func runExport(journalInstance *journal.Journal, args []string) error {
+	if len(args) > 0 && args[0] != "-" {
+		file, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+		if err := writeExport(journalInstance, file); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+	return writeExport(journalInstance, os.Stdout)
+}
+
+func writeExport(journalInstance *journal.Journal, out io.Writer) error {
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+	err := journalInstance.ForEachEntry(func(entry models.Entry) error {
+		return encoder.Encode(v1.FromEntry(entry))
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
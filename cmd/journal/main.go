@@ -2,14 +2,53 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"journal/models"
+	"journal/pkg/client"
 	"journal/pkg/journal"
 	"journal/pkg/storage"
 	"os"
+	"slices"
 	"strings"
 )
 
 func main() {
+	// Global flags come before the command, e.g. "journal --notebook work list".
+	// The remaining arguments replace os.Args so commands read them as before.
+	notebook := flag.String("notebook", "", "only work with the entries of the named notebook")
+	remote := flag.String("remote", os.Getenv(remoteEnv), "URL of a journal server to use instead of the local database (or $"+remoteEnv+")")
+	token := flag.String("token", os.Getenv(tokenEnv), "API token for the remote journal (or $"+tokenEnv+")")
+	timeout := flag.Duration("timeout", remoteTimeout(), "how long a request to the remote journal may take (or $"+timeoutEnv+")")
+	flag.Parse()
+	os.Args = append(os.Args[:1], flag.Args()...)
+	removeExpiredUnlockCaches()
+
+	// Completion scripts are generated before touching storage so that
+	// sourcing them never creates a database in the working directory.
+	if len(os.Args) > 2 && os.Args[1] == "completion" {
+		if err := writeCompletion(os.Stdout, os.Args[2]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *remote != "" {
+		if len(os.Args) > 1 && localOnly[os.Args[1]] {
+			fmt.Printf("%s is not available for a remote journal; run it on the server.\n", os.Args[1])
+			return
+		}
+		if *token == "" {
+			fmt.Printf("A remote journal needs an API token; set $%s or pass --token.\n", tokenEnv)
+			return
+		}
+		store := storage.NewHTTPStorage(*remote, *token, *timeout)
+		status := run(journal.NewJournal(store).InNotebook(*notebook), store.Client())
+		store.Close()
+		os.Exit(status)
+	}
+
 	// Create a new journal instance
 	//journalInstance := journal.NewJournal()
 
@@ -17,20 +56,65 @@ func main() {
 	dbFileName := "journal.db"
 
 	// Initialize SQLite storage
-	sqliteStorage, err := storage.NewSQLiteStorage(dbFileName)
+	sqliteStorage, err := storage.NewSQLiteStorageWithOptions(dbFileName, sqliteOptions())
 
 	if err != nil {
 		fmt.Println("Error initializing SQLite: ", err)
 		return
 	}
+	defer sqliteStorage.Close()
+
+	// The key file holds the keys entries are encrypted with, once `journal unlock` set it up
+	keyFile := dbFileName + ".key"
+
+	// These commands manage the keys themselves, so they run on the unencrypted storage
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "unlock":
+			runUnlock(sqliteStorage, keyFile)
+			return
+		case "lock":
+			runLock(keyFile)
+			return
+		case "passphrase":
+			runPassphrase(keyFile)
+			return
+		case "rotate":
+			runRotate(sqliteStorage, keyFile)
+			return
+		}
+	}
+
+	var store storage.Storage = sqliteStorage
+	encrypted, err := openEncrypted(sqliteStorage, keyFile)
+	if err != nil {
+		if len(os.Args) > 1 && os.Args[1] == completeCommand {
+			// Offer no candidates rather than completing the error message
+			sqliteStorage.Close()
+			os.Exit(1)
+		}
+		fmt.Println(err)
+		return
+	}
+	if encrypted != nil {
+		store = encrypted
+	}
 
 	// Create a new journal instance using SQLite
-	journalInstance := journal.NewJournal(sqliteStorage)
+	journalInstance := journal.NewJournal(store).InNotebook(*notebook)
+	if status := run(journalInstance, nil); status != 0 {
+		sqliteStorage.Close()
+		os.Exit(status)
+	}
+}
 
+// run carries out the command in os.Args and returns the exit status. remote
+// is the client of the remote journal's API, nil for a local journal.
+func run(journalInstance *journal.Journal, remote *client.Client) int {
 	// Check command line arguments
 	if len(os.Args) < 2 {
 		fmt.Println("usage: journal [command] [arguments]")
-		return
+		return 0
 	}
 
 	// Determine the command
@@ -40,7 +124,7 @@ func main() {
 	case "create":
 		if len(os.Args) < 4 {
 			fmt.Println("usage: journal create [title] [content]")
-			return
+			return 0
 		}
 
 		title := os.Args[2]
@@ -51,23 +135,101 @@ func main() {
 			fmt.Println(err)
 			break
 		}
-		fmt.Println("Created entry: %s\n", entry.ID)
+		fmt.Printf("Created entry: %s\n", entry.ID)
 
 	case "list":
-		// List all journal entries
+		// List all journal entries, or those with the #tag given
 		entries, err := journalInstance.ListEntries()
 		if err != nil {
 			fmt.Println(err)
 			break
 		}
+		if len(os.Args) > 2 {
+			tag := strings.ToLower(strings.TrimPrefix(os.Args[2], "#"))
+			entries = slices.DeleteFunc(entries, func(entry models.Entry) bool {
+				return !slices.Contains(journal.Tags(entry), tag)
+			})
+		}
 		if len(entries) < 1 {
 			fmt.Println("No entries found.")
-			return
+			return 0
 		}
 		for _, entry := range entries {
 			fmt.Printf(" ID: %s\n Title: %s\n Content: %s\n Created: %s\n\n", entry.ID, entry.Title, entry.Content, entry.Created)
 
 		}
+
+	case "get":
+		if len(os.Args) < 3 {
+			fmt.Println("usage: journal get [id]")
+			return 0
+		}
+		entry, err := journalInstance.GetEntry(os.Args[2])
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+		fmt.Printf("ID: %s\n Notebook: %s\n Title: %s\n Content: %s\n Created: %s\n Updated: %s\n\n", entry.ID, entry.Notebook, entry.Title, entry.Content, entry.Created, entry.Updated)
+
+	case "update":
+		if len(os.Args) < 5 {
+			fmt.Println("usage: journal update [id] [title] [content]")
+			return 0
+		}
+		entry, err := journalInstance.UpdateEntry(os.Args[2], os.Args[3], os.Args[4])
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+		fmt.Printf("Updated entry: %s\n", entry.ID)
+
+	case "delete":
+		if len(os.Args) < 3 {
+			fmt.Println("usage: journal delete [id]")
+			return 0
+		}
+		if err := journalInstance.DeleteEntry(os.Args[2]); err != nil {
+			fmt.Println(err)
+			break
+		}
+		fmt.Printf("Deleted entry: %s\n", os.Args[2])
+
+	case "move":
+		if len(os.Args) < 4 {
+			fmt.Println(`usage: journal move [id] [notebook]  (use "" to take the entry out of its notebook)`)
+			return 0
+		}
+		entry, err := journalInstance.MoveEntry(os.Args[2], os.Args[3])
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+		fmt.Printf("Moved entry %s to notebook %q\n", entry.ID, entry.Notebook)
+
+	case "notebook":
+		runNotebook(journalInstance, os.Args[2:])
+
+	case "export":
+		if err := runExport(journalInstance, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Export failed:", err)
+			return 1
+		}
+
+	case "token":
+		runToken(os.Args[2:])
+
+	case "webhooks":
+		runWebhooks(remote, os.Args[2:])
+
+	case "completion":
+		fmt.Println("usage: journal completion [bash|zsh|fish]")
+
+	case completeCommand:
+		// Invoked by the completion scripts; prints one candidate per line.
+		if err := complete(os.Stdout, journalInstance, os.Args[2:]); err != nil {
+			return 1
+		}
+
 	case "":
 	case "interactive":
 		// Create a scanner to read input from the terminal
@@ -106,7 +268,7 @@ func main() {
 						continue
 					}
 
-					fmt.Println("Created entry: %s\n", entry.ID)
+					fmt.Printf("Created entry: %s\n", entry.ID)
 
 				case "list":
 					entries, err := journalInstance.ListEntries()
@@ -178,8 +340,8 @@ func main() {
 
 	default:
 		fmt.Println("Unknown command: " + command)
-		fmt.Println("Available commands: create, list")
+		fmt.Println("Available commands: create, list, get, update, delete, interactive, move, notebook, export, token, webhooks, unlock, lock, passphrase, rotate, completion")
 
 	}
-
+	return 0
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"journal/pkg/journal"
+)
+
+const notebookUsage = `usage: journal notebook create [name]
+       journal notebook list
+       journal notebook rename [name] [new name]
+       journal notebook delete [name]`
+
+// runNotebook manages the notebooks entries are filed in
+func runNotebook(journalInstance *journal.Journal, args []string) {
+	if len(args) < 1 {
+		fmt.Println(notebookUsage)
+		return
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) < 2 {
+			fmt.Println(notebookUsage)
+			return
+		}
+		notebook, err := journalInstance.CreateNotebook(args[1])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Created notebook: %s\n", notebook.Name)
+
+	case "list":
+		notebooks, err := journalInstance.ListNotebooks()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if len(notebooks) < 1 {
+			fmt.Println("No notebooks found.")
+			return
+		}
+		for _, notebook := range notebooks {
+			entries, err := journalInstance.InNotebook(notebook.Name).ListEntries()
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf(" %s (%d entries)\n", notebook.Name, len(entries))
+		}
+
+	case "rename":
+		if len(args) < 3 {
+			fmt.Println(notebookUsage)
+			return
+		}
+		notebook, err := journalInstance.RenameNotebook(args[1], args[2])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Renamed notebook %s to %s\n", args[1], notebook.Name)
+
+	case "delete":
+		if len(args) < 2 {
+			fmt.Println(notebookUsage)
+			return
+		}
+		if err := journalInstance.DeleteNotebook(args[1]); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Deleted notebook: %s\n", args[1])
+
+	default:
+		fmt.Println(notebookUsage)
+	}
+}
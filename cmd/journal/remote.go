@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Environment variables configuring a remote journal, so the token doesn't
+// have to be passed on the command line where other users can see it.
+const (
+	remoteEnv  = "JOURNAL_URL"
+	tokenEnv   = "JOURNAL_TOKEN"
+	timeoutEnv = "JOURNAL_TIMEOUT"
+)
+
+// defaultTimeout is how long a request to a remote journal may take
+const defaultTimeout = 30 * time.Second
+
+// remoteTimeout returns the request timeout set in $JOURNAL_TIMEOUT, or the default
+func remoteTimeout() time.Duration {
+	value := os.Getenv(timeoutEnv)
+	if value == "" {
+		return defaultTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring invalid %s %q: %v\n", timeoutEnv, value, err)
+		return defaultTimeout
+	}
+	return timeout
+}
+
+// localOnly lists the commands that work on a database directly, the local
+// one or the server's, and make no sense for a remote journal.
+var localOnly = map[string]bool{
+	"unlock":     true,
+	"lock":       true,
+	"passphrase": true,
+	"rotate":     true,
+	"token":      true,
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"journal/models"
+	"journal/pkg/auth"
+	"journal/pkg/utils"
+)
+
+const tokenUsage = `usage: journal token create [username] [name] [read|read-write]
+       journal token list [username]
+       journal token revoke [username] [id]`
+
+// runToken manages the API tokens of a user in the server's database, which
+// the server checks them against
+func runToken(args []string) {
+	if len(args) < 2 {
+		fmt.Println(tokenUsage)
+		return
+	}
+	store, err := serverStore()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer store.Close()
+	user, err := store.GetUserByUsername(args[1])
+	if err != nil {
+		fmt.Printf("Unknown user %q: %v\n", args[1], err)
+		return
+	}
+
+	accounts := auth.NewAccounts(store)
+	switch args[0] {
+	case "create":
+		if len(args) < 3 {
+			fmt.Println(tokenUsage)
+			return
+		}
+		scope := models.TokenScopeRead
+		if len(args) > 3 {
+			scope = models.TokenScope(args[3])
+		}
+		secret, token, err := accounts.CreateToken(user.ID, args[2], scope)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Created %s token %s (%s)\n", token.Scope, token.ID, token.Name)
+		fmt.Println("Copy it now, it won't be shown again:")
+		fmt.Println(secret)
+
+	case "list":
+		tokens, err := accounts.ListTokens(user.ID)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if len(tokens) < 1 {
+			fmt.Println("No tokens found.")
+			return
+		}
+		for _, token := range tokens {
+			lastUsed := "never"
+			if !token.LastUsed.IsZero() {
+				lastUsed = utils.FormatTime(token.LastUsed)
+			}
+			fmt.Printf(" ID: %s\n Name: %s\n Scope: %s\n Created: %s\n Last used: %s\n\n", token.ID, token.Name, token.Scope, utils.FormatTime(token.Created), lastUsed)
+		}
+
+	case "revoke":
+		if len(args) < 3 {
+			fmt.Println(tokenUsage)
+			return
+		}
+		if err := accounts.RevokeToken(user.ID, args[2]); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Revoked token: %s\n", args[2])
+
+	default:
+		fmt.Println(tokenUsage)
+	}
+}
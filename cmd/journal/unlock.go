@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"io/fs"
+	"journal/pkg/storage"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// unlockDuration is how long `journal unlock` keeps the journal unlocked
+const unlockDuration = 12 * time.Hour
+
+// passphraseEnv lets scripts unlock the journal without a prompt
+const passphraseEnv = "JOURNAL_PASSPHRASE"
+
+var errLocked = errors.New("the journal is encrypted and locked; run `journal unlock` first")
+
+// unlockCache is what `journal unlock` remembers: the key derived from the
+// passphrase, so later commands don't have to run Argon2 or prompt again.
+// Anyone who can read the file can decrypt the journal until it expires, so
+// it is only readable by the user and removed by the first command run after
+// it expires, whichever journal that command is for.
+type unlockCache struct {
+	Key     []byte
+	Expires time.Time
+}
+
+// cacheDir returns the directory the unlocked keys are cached in, outside
+// the working directory so they never end up next to the database.
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "journal"), nil
+}
+
+// cachePath returns where the unlocked key of a key file is cached
+func cachePath(keyFile string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(keyFile)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".unlock"), nil
+}
+
+func saveUnlockCache(keyFile string, key []byte) error {
+	path, err := cachePath(keyFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(unlockCache{Key: key, Expires: time.Now().Add(unlockDuration)})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func loadUnlockCache(keyFile string) ([]byte, bool) {
+	path, err := cachePath(keyFile)
+	if err != nil {
+		return nil, false
+	}
+	return readUnlockCache(path)
+}
+
+// readUnlockCache returns the key cached in the file, removing the file if
+// the key expired or can't be read
+func readUnlockCache(path string) ([]byte, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var cache unlockCache
+	if err := json.Unmarshal(data, &cache); err != nil || time.Now().After(cache.Expires) {
+		os.Remove(path)
+		return nil, false
+	}
+	return cache.Key, true
+}
+
+// removeExpiredUnlockCaches removes every cached key that expired, so keys
+// of journals that aren't used anymore don't stay on disk. Every command
+// runs it.
+func removeExpiredUnlockCaches() {
+	dir, err := cacheDir()
+	if err != nil {
+		return
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.unlock"))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		readUnlockCache(path)
+	}
+}
+
+// openEncrypted returns the backend wrapped in an EncryptedStorage when the
+// journal has a key file, or nil if encryption isn't set up. The keyring is
+// unlocked with $JOURNAL_PASSPHRASE or the key cached by `journal unlock`.
+func openEncrypted(backend storage.Storage, keyFile string) (*storage.EncryptedStorage, error) {
+	file, err := storage.LoadKeyFile(keyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+
+	var keyring *storage.Keyring
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		keyring, err = file.Unlock(passphrase)
+	} else if key, ok := loadUnlockCache(keyFile); ok {
+		keyring, err = file.UnlockWithKey(key)
+	} else {
+		return nil, errLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewEncryptedStorage(backend, keyring)
+}
+
+// stdin is shared by the prompts so input buffered by one isn't lost to the next
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassphrase prompts for a passphrase without echoing it. When stdin is
+// not a terminal the passphrase is read as a line instead.
+func readPassphrase(prompt string) (string, error) {
+	fmt.Print(prompt)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		return string(passphrase), err
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readNewPassphrase prompts for a passphrase twice
+func readNewPassphrase() (string, error) {
+	passphrase, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+	confirm, err := readPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != confirm {
+		return "", errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// runUnlock unlocks an encrypted journal for unlockDuration. The first time
+// it sets up encryption and encrypts the existing entries.
+func runUnlock(backend storage.Storage, keyFile string) {
+	file, err := storage.LoadKeyFile(keyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		setUpEncryption(backend, keyFile)
+		return
+	}
+	if err != nil {
+		fmt.Println("Reading key file failed:", err)
+		return
+	}
+
+	passphrase, err := readPassphrase("Passphrase: ")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	key := storage.DeriveKey(passphrase, file.Salt)
+	if _, err := file.UnlockWithKey(key); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := saveUnlockCache(keyFile, key); err != nil {
+		fmt.Println("Caching the key failed:", err)
+		return
+	}
+	fmt.Printf("Journal unlocked for %s.\n", unlockDuration)
+}
+
+func setUpEncryption(backend storage.Storage, keyFile string) {
+	fmt.Println("Setting up encryption. Entries will only be readable with this passphrase; it cannot be recovered.")
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	file, keyring, err := storage.NewKeyFile(passphrase)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	encrypted, err := storage.NewEncryptedStorage(backend, keyring)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// Write the key file first so the entries are never encrypted with a key
+	// that isn't saved anywhere.
+	if err := file.Save(keyFile); err != nil {
+		fmt.Println("Writing key file failed:", err)
+		return
+	}
+	count, err := encrypted.ReencryptAll()
+	if err != nil {
+		fmt.Println("Encrypting entries failed:", err)
+		return
+	}
+	if err := saveUnlockCache(keyFile, storage.DeriveKey(passphrase, file.Salt)); err != nil {
+		fmt.Println("Caching the key failed:", err)
+		return
+	}
+	fmt.Printf("Encrypted %d entries. Keep %s safe; without it the entries cannot be read.\n", count, keyFile)
+	fmt.Printf("Journal unlocked for %s.\n", unlockDuration)
+}
+
+// runLock forgets the cached key so the passphrase is needed again
+func runLock(keyFile string) {
+	path, err := cachePath(keyFile)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("Journal locked.")
+}
+
+// runPassphrase changes the passphrase protecting the key file. Entries are
+// not touched since their keys stay the same.
+func runPassphrase(keyFile string) {
+	file, err := storage.LoadKeyFile(keyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("Encryption is not set up; run `journal unlock` to set it up.")
+		return
+	}
+	if err != nil {
+		fmt.Println("Reading key file failed:", err)
+		return
+	}
+	current, err := readPassphrase("Current passphrase: ")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	keyring, err := file.Unlock(current)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := file.Wrap(keyring, passphrase); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := file.Save(keyFile); err != nil {
+		fmt.Println("Writing key file failed:", err)
+		return
+	}
+	if err := saveUnlockCache(keyFile, storage.DeriveKey(passphrase, file.Salt)); err != nil {
+		fmt.Println("Caching the key failed:", err)
+		return
+	}
+	fmt.Println("Passphrase changed.")
+}
+
+// runRotate generates a new data key and re-encrypts every entry with it,
+// including entries that aren't encrypted. Older keys stay in the key file
+// until every entry was re-encrypted, then they are retired so values forged
+// with a leaked old key aren't accepted anymore.
+func runRotate(backend storage.Storage, keyFile string) {
+	file, err := storage.LoadKeyFile(keyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("Encryption is not set up; run `journal unlock` to set it up.")
+		return
+	}
+	if err != nil {
+		fmt.Println("Reading key file failed:", err)
+		return
+	}
+	passphrase, err := readPassphrase("Passphrase: ")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	keyring, err := file.Unlock(passphrase)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	id, err := keyring.AddKey()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := file.Wrap(keyring, passphrase); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := file.Save(keyFile); err != nil {
+		fmt.Println("Writing key file failed:", err)
+		return
+	}
+	if err := saveUnlockCache(keyFile, storage.DeriveKey(passphrase, file.Salt)); err != nil {
+		fmt.Println("Caching the key failed:", err)
+		return
+	}
+
+	encrypted, err := storage.NewEncryptedStorage(backend, keyring)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	count, err := encrypted.ReencryptAll()
+	if err != nil {
+		fmt.Println("Re-encrypting entries failed; the old keys are kept:", err)
+		return
+	}
+
+	retired := keyring.RetireOldKeys()
+	if err := file.Wrap(keyring, passphrase); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := file.Save(keyFile); err != nil {
+		fmt.Println("Writing key file failed:", err)
+		return
+	}
+	if err := saveUnlockCache(keyFile, storage.DeriveKey(passphrase, file.Salt)); err != nil {
+		fmt.Println("Caching the key failed:", err)
+		return
+	}
+	fmt.Printf("Rotated to key %s, re-encrypted %d entries and retired %d old keys.\n", id, count, len(retired))
+}
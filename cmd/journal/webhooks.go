@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"journal/models"
+	"journal/pkg/client"
+	"journal/pkg/utils"
+	"journal/pkg/webhooks"
+	"strings"
+	"time"
+)
+
+const webhookUsage = `usage: journal webhooks add [username] [url] [created,updated,deleted]
+       journal webhooks list [username]
+       journal webhooks delete [username] [id]
+       journal webhooks test [username] [id]
+       journal webhooks dead [username]
+With --remote, leave out the username: the webhooks of the token's owner are managed.`
+
+// webhookTestTimeout is how long `journal webhooks test` waits for the receiver
+const webhookTestTimeout = 15 * time.Second
+
+// webhookManager manages the webhooks of a single user, in the server's
+// database or through its API
+type webhookManager interface {
+	CreateWebhook(rawURL string, events []string) (models.Webhook, error)
+	ListWebhooks() ([]models.Webhook, error)
+	DeleteWebhook(id string) error
+	TestWebhook(ctx context.Context, id string) error
+	DeadLetters() ([]models.DeadLetter, error)
+}
+
+// userWebhooks manages the webhooks of a user in a database
+type userWebhooks struct {
+	hooks *webhooks.Webhooks
+	owner string
+}
+
+func (u userWebhooks) CreateWebhook(rawURL string, events []string) (models.Webhook, error) {
+	return u.hooks.Create(u.owner, rawURL, events)
+}
+
+func (u userWebhooks) ListWebhooks() ([]models.Webhook, error) {
+	return u.hooks.List(u.owner)
+}
+
+func (u userWebhooks) DeleteWebhook(id string) error {
+	return u.hooks.Delete(u.owner, id)
+}
+
+func (u userWebhooks) TestWebhook(ctx context.Context, id string) error {
+	return u.hooks.Test(ctx, u.owner, id)
+}
+
+func (u userWebhooks) DeadLetters() ([]models.DeadLetter, error) {
+	return u.hooks.DeadLetters(u.owner)
+}
+
+// runWebhooks manages webhooks through the remote server's API, or else
+// those of the named user in the server's database, which the server
+// delivers from.
+func runWebhooks(remote *client.Client, args []string) {
+	var hooks webhookManager
+	if remote != nil {
+		hooks = remote
+	} else {
+		if len(args) < 2 {
+			fmt.Println(webhookUsage)
+			return
+		}
+		store, err := serverStore()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer store.Close()
+		user, err := store.GetUserByUsername(args[1])
+		if err != nil {
+			fmt.Printf("Unknown user %q: %v\n", args[1], err)
+			return
+		}
+		hooks = userWebhooks{hooks: webhooks.New(store), owner: user.ID}
+		// The remaining arguments are then the same as with --remote
+		args = append(args[:1], args[2:]...)
+	}
+	if len(args) < 1 {
+		fmt.Println(webhookUsage)
+		return
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			fmt.Println(webhookUsage)
+			return
+		}
+		var events []string
+		if len(args) > 2 && args[2] != "" {
+			events = strings.Split(args[2], ",")
+		}
+		webhook, err := hooks.CreateWebhook(args[1], events)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Created webhook %s for %s\n", webhook.ID, webhook.URL)
+		fmt.Println("Deliveries are signed with this secret:")
+		fmt.Println(webhook.Secret)
+
+	case "list":
+		list, err := hooks.ListWebhooks()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if len(list) < 1 {
+			fmt.Println("No webhooks found.")
+			return
+		}
+		for _, webhook := range list {
+			events := "all"
+			if len(webhook.Events) > 0 {
+				events = strings.Join(webhook.Events, ", ")
+			}
+			fmt.Printf(" ID: %s\n URL: %s\n Events: %s\n Created: %s\n\n", webhook.ID, webhook.URL, events, utils.FormatTime(webhook.Created))
+		}
+
+	case "delete":
+		if len(args) < 2 {
+			fmt.Println(webhookUsage)
+			return
+		}
+		if err := hooks.DeleteWebhook(args[1]); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Deleted webhook: %s\n", args[1])
+
+	case "test":
+		if len(args) < 2 {
+			fmt.Println(webhookUsage)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), webhookTestTimeout)
+		defer cancel()
+		if err := hooks.TestWebhook(ctx, args[1]); err != nil {
+			fmt.Println("Test delivery failed:", err)
+			return
+		}
+		fmt.Printf("Test event delivered to webhook %s\n", args[1])
+
+	case "dead":
+		letters, err := hooks.DeadLetters()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if len(letters) < 1 {
+			fmt.Println("No failed deliveries.")
+			return
+		}
+		for _, letter := range letters {
+			fmt.Printf(" ID: %s\n Webhook: %s (%s)\n Event: %s\n Attempts: %d\n Error: %s\n Failed: %s\n Payload: %s\n\n",
+				letter.ID, letter.WebhookID, letter.URL, letter.Event, letter.Attempts, letter.Error, utils.FormatTime(letter.Created), letter.Payload)
+		}
+
+	default:
+		fmt.Println(webhookUsage)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"io"
+	"journal/pkg/api/v1"
+	"journal/pkg/journal"
+	"journal/pkg/webhooks"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// maxBodySize caps the size of API request bodies
+const maxBodySize = 1 << 20
+
+// apiError is an error that knows the HTTP status it should be reported with.
+type apiError struct {
+	Status int
+	Detail string
+	Err    error // Underlying error, logged but never shown to clients
+}
+
+func (e *apiError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *apiError) Unwrap() error {
+	return e.Err
+}
+
+// errBadRequest reports a request the client should fix before retrying
+func errBadRequest(format string, args ...any) error {
+	return &apiError{Status: http.StatusBadRequest, Detail: fmt.Sprintf(format, args...)}
+}
+
+// errUnprocessable reports a well-formed request with invalid values
+func errUnprocessable(format string, args ...any) error {
+	return &apiError{Status: http.StatusUnprocessableEntity, Detail: fmt.Sprintf(format, args...)}
+}
+
+// apiHandler is an API endpoint. Returned errors are written as problem+json,
+// so handlers only write the response themselves on success.
+type apiHandler func(w http.ResponseWriter, r *http.Request) error
+
+func (h apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		writeError(w, r, err)
+	}
+}
+
+// errorStatus returns the HTTP status code an error is reported with
+func errorStatus(err error) int {
+	var apiErr *apiError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.Status
+	case errors.Is(err, journal.ErrEntryNotFound), errors.Is(err, journal.ErrNotebookNotFound), errors.Is(err, webhooks.ErrWebhookNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, journal.ErrEntryExists), errors.Is(err, journal.ErrNotebookExists), errors.Is(err, journal.ErrNotebookNotEmpty):
+		return http.StatusConflict
+	case errors.Is(err, journal.ErrInvalidEntryID), errors.Is(err, journal.ErrInvalidNotebookName),
+		errors.Is(err, webhooks.ErrInvalidURL), errors.Is(err, webhooks.ErrInvalidEvent):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// problemTypes are the problem types of the journal's and webhooks' errors
+var problemTypes = []struct {
+	err         error
+	problemType string
+}{
+	{journal.ErrEntryNotFound, v1.ProblemEntryNotFound},
+	{journal.ErrEntryExists, v1.ProblemEntryExists},
+	{journal.ErrInvalidEntryID, v1.ProblemInvalidEntryID},
+	{journal.ErrNotebookNotFound, v1.ProblemNotebookNotFound},
+	{journal.ErrNotebookExists, v1.ProblemNotebookExists},
+	{journal.ErrNotebookNotEmpty, v1.ProblemNotebookNotEmpty},
+	{journal.ErrInvalidNotebookName, v1.ProblemInvalidNotebookName},
+	{webhooks.ErrWebhookNotFound, v1.ProblemWebhookNotFound},
+	{webhooks.ErrInvalidURL, v1.ProblemInvalidWebhookURL},
+	{webhooks.ErrInvalidEvent, v1.ProblemInvalidWebhookEvent},
+}
+
+// errorType returns the problem type an error is reported with
+func errorType(err error) string {
+	for _, known := range problemTypes {
+		if errors.Is(err, known.err) {
+			return known.problemType
+		}
+	}
+	return "about:blank"
+}
+
+// writeError reports the error as problem+json
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	sendProblem(w, errorProblem(r, err))
+}
+
+// writeProblem writes a problem+json response with the given status
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	sendProblem(w, newProblem(r, status, detail))
+}
+
+// errorProblem describes the error as problem details. Unexpected errors are
+// logged and described without their details.
+func errorProblem(r *http.Request, err error) v1.Problem {
+	status := errorStatus(err)
+	detail := err.Error()
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		detail = apiErr.Detail
+	}
+	if status == http.StatusInternalServerError {
+		requestLogger(r).Error("Request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+		detail = ""
+	}
+	problem := newProblem(r, status, detail)
+	problem.Type = errorType(err)
+	return problem
+}
+
+func newProblem(r *http.Request, status int, detail string) v1.Problem {
+	return v1.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+func sendProblem(w http.ResponseWriter, problem v1.Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		slog.Error("Sending error response failed", "err", err)
+	}
+}
+
+// writeJSON encodes the value as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Sending response failed", "err", err)
+	}
+}
+
+// decodeJSON reads the request body into v, rejecting malformed, oversized
+// and trailing input with a 400.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err := decoder.Decode(v); err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, io.EOF):
+			return errBadRequest("request body must not be empty")
+		case errors.As(err, &maxErr):
+			return &apiError{Status: http.StatusRequestEntityTooLarge, Detail: fmt.Sprintf("request body must not be larger than %d bytes", maxBodySize)}
+		default:
+			return errBadRequest("request body is not valid JSON: %v", err)
+		}
+	}
+	if decoder.More() {
+		return errBadRequest("request body must contain a single JSON object")
+	}
+	return nil
+}
+
+// OpenAPIHandler serves the OpenAPI document describing the API
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(v1.OpenAPI); err != nil {
+		requestLogger(r).Error("Sending OpenAPI document failed", "err", err)
+	}
+}
+
+// isAPIRequest reports whether the request is for the REST API
+func isAPIRequest(r *http.Request) bool {
+	return r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// allowedMethods returns the methods the router has a route for at the request's path
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var methods []string
+	for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE"} {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// notFoundHandler answers unknown API routes with problem+json and other
+// routes with the 404 page. mux doesn't reliably tell a wrong method from an
+// unknown path once subrouters are involved, so API requests are checked for
+// a route with another method and answered with 405 if there is one.
+func notFoundHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAPIRequest(r) {
+			NotFoundPageHandler(w, r)
+			return
+		}
+		if methods := allowedMethods(router, r); len(methods) > 0 {
+			writeMethodNotAllowed(w, r, methods)
+			return
+		}
+		writeProblem(w, r, http.StatusNotFound, "no such endpoint")
+	})
+}
+
+// methodNotAllowedHandler answers requests with a method the route doesn't support
+func methodNotAllowedHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAPIRequest(r) {
+			w.Header().Set("Allow", strings.Join(allowedMethods(router, r), ", "))
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		writeMethodNotAllowed(w, r, allowedMethods(router, r))
+	})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, methods []string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeProblem(w, r, http.StatusMethodNotAllowed, r.Method+" is not supported for this endpoint")
+}
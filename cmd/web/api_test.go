@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"journal/pkg/api/v1"
+	"journal/pkg/journal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteErrorProblemType(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		want   string
+	}{
+		{journal.ErrEntryNotFound, http.StatusNotFound, v1.ProblemEntryNotFound},
+		{fmt.Errorf("moving entry: %w", journal.ErrNotebookNotFound), http.StatusNotFound, v1.ProblemNotebookNotFound},
+		{journal.ErrNotebookNotEmpty, http.StatusConflict, v1.ProblemNotebookNotEmpty},
+		{errUnprocessable("title is required"), http.StatusUnprocessableEntity, "about:blank"},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		writeError(recorder, httptest.NewRequest("GET", "/api/entries/id", nil), test.err)
+
+		var problem v1.Problem
+		if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+			t.Fatalf("%v: decoding the problem: %v", test.err, err)
+		}
+		if problem.Status != test.status || recorder.Code != test.status {
+			t.Errorf("%v: got status %d, want %d", test.err, recorder.Code, test.status)
+		}
+		if problem.Type != test.want {
+			t.Errorf("%v: got type %s, want %s", test.err, problem.Type, test.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"journal/models"
+	"journal/pkg/auth"
+	"journal/pkg/journal"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sessionCookie holds the token of the logged-in user's session.
+const sessionCookie = "journal_session"
+
+type contextKey string
+
+// userKey is the request context key of the authenticated models.User.
+const userKey contextKey = "user"
+
+// scopeKey is the request context key of the models.TokenScope of an API
+// request; requests authenticated with a session may do anything.
+const scopeKey contextKey = "scope"
+
+var accountsInstance *auth.Accounts
+
+// insecureCookies drops the Secure flag from session cookies so they are sent over plain HTTP.
+var insecureCookies bool
+
+// currentUser returns the user authenticated for the request
+func currentUser(r *http.Request) models.User {
+	user, _ := r.Context().Value(userKey).(models.User)
+	return user
+}
+
+// userJournal returns the journal holding the entries of the request's user,
+// limited to the notebook named in the route if there is one
+func userJournal(r *http.Request) *journal.Journal {
+	return journalIntance.ForUser(currentUser(r).ID).InNotebook(mux.Vars(r)["nb"]).WithLogger(requestLogger(r))
+}
+
+// sessionUser looks up the user of the request's session cookie
+func sessionUser(r *http.Request) (models.User, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return models.User{}, false
+	}
+	user, err := accountsInstance.Authenticate(cookie.Value)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidSession) {
+			requestLogger(r).Error("Authenticating session failed", "err", err)
+		}
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// requireUser redirects visitors without a valid session to the login page
+func requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := sessionUser(r)
+		if !ok {
+			login := "/app/login?next=" + url.QueryEscape(r.URL.RequestURI())
+			if isHTMX(r) {
+				// A redirect would be followed by htmx and swapped into the page
+				w.Header().Set("HX-Redirect", login)
+				return
+			}
+			http.Redirect(w, r, login, http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+	})
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// requireAPIUser rejects API requests without a valid API token or session.
+// Requests made with a read-only token are limited to safe methods.
+func requireAPIUser(next http.Handler) http.Handler {
+	return authenticateAPI(next, true)
+}
+
+// requireGraphQLUser is requireAPIUser for the GraphQL endpoint, where
+// queries are POSTed as well, so the scope of the token is left to the
+// mutations to check.
+func requireGraphQLUser(next http.Handler) http.Handler {
+	return authenticateAPI(next, false)
+}
+
+// canWrite reports whether the request may change entries
+func canWrite(ctx context.Context) bool {
+	scope, ok := ctx.Value(scopeKey).(models.TokenScope)
+	return !ok || scope == models.TokenScopeReadWrite
+}
+
+func authenticateAPI(next http.Handler, checkMethod bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := bearerToken(r)
+		if !ok {
+			user, ok := sessionUser(r)
+			if !ok {
+				writeProblem(w, r, http.StatusUnauthorized, "log in or send an API token in an Authorization: Bearer header")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+			return
+		}
+
+		user, token, err := accountsInstance.AuthenticateToken(secret)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeProblem(w, r, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, r, fmt.Errorf("authenticating API token: %w", err))
+			return
+		}
+		if checkMethod && !auth.Allows(token.Scope, r.Method) {
+			writeProblem(w, r, http.StatusForbidden, "this API token is read-only")
+			return
+		}
+		ctx := context.WithValue(r.Context(), userKey, user)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, scopeKey, token.Scope)))
+	})
+}
+
+// safeNext returns the path to continue to after logging in, only allowing paths within the app
+func safeNext(next string) string {
+	if next == "/app" || strings.HasPrefix(next, "/app/") || strings.HasPrefix(next, "/app?") {
+		return next
+	}
+	return "/app"
+}
+
+func setSessionCookie(w http.ResponseWriter, token string, session models.Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.Expires,
+		HttpOnly: true,
+		Secure:   !insecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   !insecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// LoginPageHandler shows the login form
+func LoginPageHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := sessionUser(r); ok {
+		http.Redirect(w, r, "/app", http.StatusSeeOther)
+		return
+	}
+	data := PageData{
+		Title: "Log In",
+		Flash: popFlash(w, r),
+		Next:  safeNext(r.URL.Query().Get("next")),
+	}
+	pageTemplates.render(w, http.StatusOK, "login", data)
+}
+
+// PostLoginHandler starts a session for valid credentials
+func PostLoginHandler(w http.ResponseWriter, r *http.Request) {
+	data := PageData{
+		Title: "Log In",
+		Next:  safeNext(r.FormValue("next")),
+	}
+	token, session, err := accountsInstance.Login(r.FormValue("username"), r.FormValue("password"))
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		data.Error = "Invalid username or password."
+		data.User.Username = r.FormValue("username")
+		pageTemplates.render(w, http.StatusUnauthorized, "login", data)
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Logging in failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
+	setSessionCookie(w, token, session)
+	http.Redirect(w, r, data.Next, http.StatusSeeOther)
+}
+
+// SignupPageHandler shows the signup form
+func SignupPageHandler(w http.ResponseWriter, r *http.Request) {
+	data := PageData{
+		Title: "Sign Up",
+	}
+	pageTemplates.render(w, http.StatusOK, "signup", data)
+}
+
+// PostSignupHandler creates an account and logs the new user in
+func PostSignupHandler(w http.ResponseWriter, r *http.Request) {
+	data := PageData{
+		Title: "Sign Up",
+	}
+	username := r.FormValue("username")
+	password := r.FormValue("password")
+	data.User.Username = username
+
+	if password != r.FormValue("confirm") {
+		data.Error = "Passwords do not match."
+		pageTemplates.render(w, http.StatusOK, "signup", data)
+		return
+	}
+	user, err := accountsInstance.Signup(username, password)
+	if errors.Is(err, auth.ErrUsernameTaken) || errors.Is(err, auth.ErrUsernameRequired) || errors.Is(err, auth.ErrPasswordTooShort) {
+		data.Error = err.Error()
+		pageTemplates.render(w, http.StatusOK, "signup", data)
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Signing up failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
+
+	token, session, err := accountsInstance.Login(username, password)
+	if err != nil {
+		requestLogger(r).Error("Logging in failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
+	setSessionCookie(w, token, session)
+	setFlash(w, "Welcome, "+user.Username+"!")
+	http.Redirect(w, r, "/app", http.StatusSeeOther)
+}
+
+// PostLogoutHandler ends the current session
+func PostLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if err := accountsInstance.Logout(cookie.Value); err != nil {
+			requestLogger(r).Error("Logging out failed", "err", err)
+		}
+	}
+	clearSessionCookie(w)
+	setFlash(w, "You have been logged out.")
+	http.Redirect(w, r, "/app/login", http.StatusSeeOther)
+}
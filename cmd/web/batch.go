@@ -0,0 +1,75 @@
+package main
+
+import (
+	"journal/pkg/api/v1"
+	"journal/pkg/journal"
+	"net/http"
+)
+
+// maxBatchSize caps the number of operations in a batch request
+const maxBatchSize = 100
+
+// BatchEntries runs several entry operations in one request. Every operation
+// succeeds or fails on its own and the response lists their results in order,
+// so a client can tell which ones to retry. Operations that succeeded are not
+// rolled back when others fail, as the API documents. Storage.SaveEntries
+// isn't used since it can't delete, and would fail every operation for one.
+func BatchEntries(w http.ResponseWriter, r *http.Request) error {
+	var batch v1.BatchRequest
+	if err := decodeJSON(w, r, &batch); err != nil {
+		return err
+	}
+	if len(batch.Operations) == 0 {
+		return errUnprocessable("operations must not be empty")
+	}
+	if len(batch.Operations) > maxBatchSize {
+		return errUnprocessable("a batch must not hold more than %d operations", maxBatchSize)
+	}
+
+	entries := userJournal(r)
+	results := make([]v1.BatchResult, len(batch.Operations))
+	for i, op := range batch.Operations {
+		result, err := runBatchOperation(entries, op)
+		if err != nil {
+			problem := errorProblem(r, err)
+			result = v1.BatchResult{Status: problem.Status, Error: &problem}
+		}
+		results[i] = result
+	}
+	writeJSON(w, http.StatusOK, v1.BatchResponse{Results: results})
+	return nil
+}
+
+// runBatchOperation carries out a single operation of a batch
+func runBatchOperation(entries *journal.Journal, op v1.BatchOperation) (v1.BatchResult, error) {
+	switch op.Op {
+	case v1.BatchCreate:
+		entry, err := createEntry(entries, v1.EntryInput{ID: op.ID, Title: op.Title.Value, Content: op.Content.Value})
+		if err != nil {
+			return v1.BatchResult{}, err
+		}
+		created := v1.FromEntry(entry)
+		return v1.BatchResult{Status: http.StatusCreated, Entry: &created}, nil
+
+	case v1.BatchUpdate:
+		if op.ID == "" {
+			return v1.BatchResult{}, errUnprocessable("id is required")
+		}
+		entry, err := patchEntry(entries, op.ID, v1.EntryPatch{Title: op.Title, Content: op.Content})
+		if err != nil {
+			return v1.BatchResult{}, err
+		}
+		updated := v1.FromEntry(entry)
+		return v1.BatchResult{Status: http.StatusOK, Entry: &updated}, nil
+
+	case v1.BatchDelete:
+		if op.ID == "" {
+			return v1.BatchResult{}, errUnprocessable("id is required")
+		}
+		if err := entries.DeleteEntry(op.ID); err != nil {
+			return v1.BatchResult{}, err
+		}
+		return v1.BatchResult{Status: http.StatusNoContent}, nil
+	}
+	return v1.BatchResult{}, errUnprocessable("op must be %q, %q or %q", v1.BatchCreate, v1.BatchUpdate, v1.BatchDelete)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"journal/pkg/journal"
+	"journal/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTestJournal points the handlers at a journal in a fresh SQLite database
+func useTestJournal(t *testing.T) {
+	t.Helper()
+	db, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := journalIntance
+	journalIntance = journal.NewJournal(db)
+	t.Cleanup(func() {
+		journalIntance = previous
+		db.Close()
+	})
+}
+
+// runBatch sends the batch as the user and returns the results
+func runBatch(t *testing.T, userID, body string) []v1.BatchResult {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/api/entries/batch", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r = r.WithContext(context.WithValue(r.Context(), userKey, models.User{ID: userID}))
+	recorder := httptest.NewRecorder()
+	apiHandler(BatchEntries).ServeHTTP(recorder, r)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+	var response v1.BatchResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Results
+}
+
+func TestBatchEntriesAppliesOperationsOnTheirOwn(t *testing.T) {
+	useTestJournal(t)
+	results := runBatch(t, "user", `{"operations": [
+		{"op": "create", "id": "kept", "title": "Title", "content": "Content"},
+		{"op": "create", "id": "kept", "title": "Again", "content": "Content"},
+		{"op": "delete", "id": "missing"}
+	]}`)
+
+	want := []struct {
+		status  int
+		problem string
+	}{
+		{http.StatusCreated, ""},
+		{http.StatusConflict, v1.ProblemEntryExists},
+		{http.StatusNotFound, v1.ProblemEntryNotFound},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Status != want[i].status {
+			t.Errorf("operation %d: got status %d, want %d", i, result.Status, want[i].status)
+		}
+		if want[i].problem != "" && (result.Error == nil || result.Error.Type != want[i].problem) {
+			t.Errorf("operation %d: got error %+v, want type %s", i, result.Error, want[i].problem)
+		}
+	}
+
+	// The failed operations don't roll back the first
+	entry, err := journalIntance.ForUser("user").GetEntry("kept")
+	if err != nil {
+		t.Fatalf("the created entry wasn't kept: %v", err)
+	}
+	if entry.Title != "Title" {
+		t.Errorf("got title %q, want the first create's", entry.Title)
+	}
+}
+
+func TestBatchEntriesConflictsWithOtherUsersIDs(t *testing.T) {
+	useTestJournal(t)
+	runBatch(t, "owner", `{"operations": [{"op": "create", "id": "taken", "title": "Title", "content": "Content"}]}`)
+
+	results := runBatch(t, "other", `{"operations": [
+		{"op": "create", "id": "taken", "title": "Title", "content": "Content"},
+		{"op": "delete", "id": "taken"}
+	]}`)
+	if results[0].Status != http.StatusConflict || results[0].Error == nil || results[0].Error.Type != v1.ProblemEntryExists {
+		t.Errorf("create: got %d %+v, want 409 %s", results[0].Status, results[0].Error, v1.ProblemEntryExists)
+	}
+	if results[1].Status != http.StatusNotFound {
+		t.Errorf("delete: got %d, want 404 for another user's entry", results[1].Status)
+	}
+	if _, err := journalIntance.ForUser("owner").GetEntry("taken"); err != nil {
+		t.Errorf("the owner's entry is gone: %v", err)
+	}
+}
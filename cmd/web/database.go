@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"journal/pkg/storage"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// Environment variables configuring the connection pool of the database.
+// The flags of the same name override them.
+const (
+	dbMaxPoolEnv  = "JOURNAL_DB_MAX_POOL"
+	dbMinPoolEnv  = "JOURNAL_DB_MIN_POOL"
+	dbIdleTimeEnv = "JOURNAL_DB_IDLE_TIME"
+	dbTimeoutEnv  = "JOURNAL_DB_TIMEOUT"
+)
+
+// databaseFlags registers the flags configuring the database connections,
+// with defaults taken from the environment. Zero keeps the MongoDB driver's
+// defaults or those set in $MONGODB_URI.
+func databaseFlags() *storage.MongoDBOptions {
+	options := &storage.MongoDBOptions{}
+	flag.Uint64Var(&options.MaxPoolSize, "db-max-pool", envUint(dbMaxPoolEnv), "most connections open to each database server (or $"+dbMaxPoolEnv+")")
+	flag.Uint64Var(&options.MinPoolSize, "db-min-pool", envUint(dbMinPoolEnv), "connections kept open to each database server (or $"+dbMinPoolEnv+")")
+	flag.DurationVar(&options.MaxConnIdleTime, "db-idle-time", envDuration(dbIdleTimeEnv, 0), "how long an unused database connection stays open (or $"+dbIdleTimeEnv+")")
+	flag.DurationVar(&options.Timeout, "db-timeout", envDuration(dbTimeoutEnv, 0), "how long a database operation may take (or $"+dbTimeoutEnv+")")
+	return options
+}
+
+// envUint returns the number set in the environment variable, or zero when
+// it isn't set or isn't a number
+func envUint(name string) uint64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		slog.Warn("Ignoring invalid setting", "name", name, "value", value, "err", err)
+		return 0
+	}
+	return number
+}
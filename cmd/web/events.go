@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"journal/pkg/api/v1"
+	"net/http"
+	"time"
+)
+
+// eventKeepAlive is how often a comment is sent on an idle event stream so
+// proxies don't close the connection
+const eventKeepAlive = 30 * time.Second
+
+// streamsDone is closed when the server starts shutting down, ending the event
+// streams so clients reconnect to another server
+var streamsDone = make(chan struct{})
+
+// Events streams changes to the user's entries as server-sent events named
+// after the change: created, updated or deleted.
+func Events(w http.ResponseWriter, r *http.Request) error {
+	controller := http.NewResponseController(w)
+	// The stream stays open for as long as the client listens, so it must not
+	// be cut off by the server's write timeout.
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	events, unsubscribe := userJournal(r).Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// Ask browsers to reconnect quickly if the connection drops
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		if err := controller.Flush(); err != nil {
+			return nil
+		}
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-streamsDone:
+			return nil
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-events:
+			data, err := json.Marshal(v1.FromEvent(string(event.Type), event.Entry, event.Time))
+			if err != nil {
+				requestLogger(r).Error("Encoding event failed", "err", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// flashCookie holds a one-off message shown on the page a redirect lands on.
+const flashCookie = "flash"
+
+// setFlash stores a message to display after the next redirect
+func setFlash(w http.ResponseWriter, message string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookie,
+		Value:    url.QueryEscape(message),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// popFlash returns the pending flash message, if any, and clears it
+func popFlash(w http.ResponseWriter, r *http.Request) string {
+	cookie, err := r.Cookie(flashCookie)
+	if err != nil {
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	message, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return ""
+	}
+	return message
+}
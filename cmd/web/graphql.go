@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/graphql-go/graphql"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"journal/pkg/journal"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPageSize is how many entries the entries query returns unless asked otherwise
+	defaultPageSize = 50
+	// maxPageSize caps how many entries the entries query returns at once
+	maxPageSize = 500
+)
+
+// graphQLRequest is the body of a GraphQL request, or its query parameters for GET
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// postedKey marks the context of GraphQL requests sent with POST, the only
+// ones allowed to run mutations
+const postedKey contextKey = "posted"
+
+// GraphQLHandler runs GraphQL queries and mutations on the user's entries,
+// sent as JSON with POST or as query parameters with GET.
+func GraphQLHandler(w http.ResponseWriter, r *http.Request) error {
+	var request graphQLRequest
+	if r.Method == "GET" {
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				return errBadRequest("variables are not a valid JSON object: %v", err)
+			}
+		}
+	} else if err := decodeJSON(w, r, &request); err != nil {
+		return err
+	}
+	if strings.TrimSpace(request.Query) == "" {
+		return errBadRequest("query is required")
+	}
+
+	ctx := context.WithValue(r.Context(), postedKey, r.Method == "POST")
+	result := graphql.Do(graphql.Params{
+		Schema:         graphQLSchema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        ctx,
+	})
+	writeJSON(w, http.StatusOK, result)
+	return nil
+}
+
+// resolverError is an error reported in the errors of a GraphQL response,
+// with a machine-readable code in its extensions
+type resolverError struct {
+	message string
+	code    string
+}
+
+func (e *resolverError) Error() string {
+	return e.message
+}
+
+func (e *resolverError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+// resolverCodes are the error codes of the HTTP statuses errors are reported with
+var resolverCodes = map[int]string{
+	http.StatusBadRequest:          "BAD_USER_INPUT",
+	http.StatusUnprocessableEntity: "BAD_USER_INPUT",
+	http.StatusForbidden:           "FORBIDDEN",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusConflict:            "CONFLICT",
+}
+
+// graphQLError describes an error for a GraphQL response the way errorProblem
+// does for the REST API. Unexpected errors are logged and not described.
+func graphQLError(ctx context.Context, err error) error {
+	status := errorStatus(err)
+	code, ok := resolverCodes[status]
+	if !ok {
+		contextLogger(ctx).Error("GraphQL request failed", "err", err)
+		return &resolverError{message: "internal error", code: "INTERNAL"}
+	}
+	message := err.Error()
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		message = apiErr.Detail
+	}
+	return &resolverError{message: message, code: code}
+}
+
+// resolve wraps a resolver so its errors are described by graphQLError
+func resolve(fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		result, err := fn(p)
+		if err != nil {
+			return nil, graphQLError(p.Context, err)
+		}
+		return result, nil
+	}
+}
+
+// requireWrite fails mutations made with a read-only token or with GET
+func requireWrite(ctx context.Context) error {
+	if !canWrite(ctx) {
+		return &apiError{Status: http.StatusForbidden, Detail: "this API token is read-only"}
+	}
+	if posted, _ := ctx.Value(postedKey).(bool); !posted {
+		return &apiError{Status: http.StatusForbidden, Detail: "mutations must be sent with POST"}
+	}
+	return nil
+}
+
+// graphQLJournal returns the journal of the request's user, limited to the
+// notebook named by the notebook argument if there is one
+func graphQLJournal(p graphql.ResolveParams) (*journal.Journal, error) {
+	user, _ := p.Context.Value(userKey).(models.User)
+	entries := journalIntance.ForUser(user.ID).WithLogger(contextLogger(p.Context))
+	notebook, _ := p.Args["notebook"].(string)
+	if notebook == "" {
+		return entries, nil
+	}
+	if _, err := entries.GetNotebook(notebook); err != nil {
+		return nil, err
+	}
+	return entries.InNotebook(notebook), nil
+}
+
+var notebookArg = &graphql.ArgumentConfig{
+	Type:        graphql.String,
+	Description: "Only work with the entries of this notebook; every notebook when left out.",
+}
+
+var entryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Entry",
+	Fields: graphql.Fields{
+		"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"notebook": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Empty if the entry isn't in a notebook."},
+		"title":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"content":  &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Markdown body of the entry."},
+		"created":  &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"updated":  &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"tags": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Description: "The #hashtags in the title and content, lowercased and without the #.",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return journal.Tags(v1.ToEntry(p.Source.(v1.Entry))), nil
+			},
+		},
+	},
+})
+
+var entryPageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "EntryPage",
+	Fields: graphql.Fields{
+		"entries":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(entryType)))},
+		"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "How many entries match, on every page."},
+		"hasMore":    &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Whether there are entries after this page."},
+	},
+})
+
+// countType is the number of entries with a notebook or tag
+var countType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Count",
+	Fields: graphql.Fields{
+		"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var statsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Stats",
+	Fields: graphql.Fields{
+		"entries":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"notebooks": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(countType))), Description: "Entries per notebook, most first; entries outside notebooks count under an empty name."},
+		"tags":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(countType))), Description: "Entries per tag, most first."},
+		"first":     &graphql.Field{Type: graphql.DateTime, Description: "When the oldest entry was created."},
+		"last":      &graphql.Field{Type: graphql.DateTime, Description: "When the newest entry was created."},
+	},
+})
+
+var sortType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "EntrySort",
+	Values: graphql.EnumValueConfigMap{
+		"NEWEST":  &graphql.EnumValueConfig{Value: "newest", Description: "Most recently created first."},
+		"OLDEST":  &graphql.EnumValueConfig{Value: "oldest"},
+		"UPDATED": &graphql.EnumValueConfig{Value: "updated", Description: "Most recently updated first."},
+		"TITLE":   &graphql.EnumValueConfig{Value: "title"},
+	},
+})
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"entries": &graphql.Field{
+			Type:        graphql.NewNonNull(entryPageType),
+			Description: "A page of the entries matching every filter given.",
+			Args: graphql.FieldConfigArgument{
+				"notebook":      notebookArg,
+				"search":        &graphql.ArgumentConfig{Type: graphql.String, Description: "Text the title or content contains, ignoring case."},
+				"tag":           &graphql.ArgumentConfig{Type: graphql.String, Description: "Tag the entry has, with or without the #."},
+				"createdAfter":  &graphql.ArgumentConfig{Type: graphql.DateTime},
+				"createdBefore": &graphql.ArgumentConfig{Type: graphql.DateTime},
+				"sort":          &graphql.ArgumentConfig{Type: sortType, DefaultValue: "newest"},
+				"limit":         &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize, Description: "At most 500."},
+				"offset":        &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+			},
+			Resolve: resolve(resolveEntries),
+		},
+		"entry": &graphql.Field{
+			Type:        entryType,
+			Description: "The entry with the ID, or null if there is none.",
+			Args: graphql.FieldConfigArgument{
+				"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				"notebook": notebookArg,
+			},
+			Resolve: resolve(func(p graphql.ResolveParams) (any, error) {
+				entries, err := graphQLJournal(p)
+				if err != nil {
+					return nil, err
+				}
+				entry, err := entries.GetEntry(p.Args["id"].(string))
+				if errors.Is(err, journal.ErrEntryNotFound) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				return v1.FromEntry(entry), nil
+			}),
+		},
+		"stats": &graphql.Field{
+			Type:        graphql.NewNonNull(statsType),
+			Description: "Counts of the entries, by notebook and by tag.",
+			Args:        graphql.FieldConfigArgument{"notebook": notebookArg},
+			Resolve:     resolve(resolveStats),
+		},
+	},
+})
+
+var mutationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Mutation",
+	Fields: graphql.Fields{
+		"createEntry": &graphql.Field{
+			Type: graphql.NewNonNull(entryType),
+			Args: graphql.FieldConfigArgument{
+				"notebook": notebookArg,
+				"id":       &graphql.ArgumentConfig{Type: graphql.ID, Description: "ID for the new entry; one is generated when left out."},
+				"title":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"content":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: resolve(func(p graphql.ResolveParams) (any, error) {
+				if err := requireWrite(p.Context); err != nil {
+					return nil, err
+				}
+				entries, err := graphQLJournal(p)
+				if err != nil {
+					return nil, err
+				}
+				id, _ := p.Args["id"].(string)
+				entry, err := createEntry(entries, v1.EntryInput{ID: id, Title: p.Args["title"].(string), Content: p.Args["content"].(string)})
+				if err != nil {
+					return nil, err
+				}
+				return v1.FromEntry(entry), nil
+			}),
+		},
+		"updateEntry": &graphql.Field{
+			Type:        graphql.NewNonNull(entryType),
+			Description: "Changes the title or content given and leaves the other as it is.",
+			Args: graphql.FieldConfigArgument{
+				"notebook": notebookArg,
+				"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				"title":    &graphql.ArgumentConfig{Type: graphql.String},
+				"content":  &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: resolve(func(p graphql.ResolveParams) (any, error) {
+				if err := requireWrite(p.Context); err != nil {
+					return nil, err
+				}
+				entries, err := graphQLJournal(p)
+				if err != nil {
+					return nil, err
+				}
+				var patch v1.EntryPatch
+				if title, ok := p.Args["title"].(string); ok {
+					patch.Title = v1.Some(title)
+				}
+				if content, ok := p.Args["content"].(string); ok {
+					patch.Content = v1.Some(content)
+				}
+				entry, err := patchEntry(entries, p.Args["id"].(string), patch)
+				if err != nil {
+					return nil, err
+				}
+				return v1.FromEntry(entry), nil
+			}),
+		},
+		"deleteEntry": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Deletes an entry and returns its ID.",
+			Args: graphql.FieldConfigArgument{
+				"notebook": notebookArg,
+				"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: resolve(func(p graphql.ResolveParams) (any, error) {
+				if err := requireWrite(p.Context); err != nil {
+					return nil, err
+				}
+				entries, err := graphQLJournal(p)
+				if err != nil {
+					return nil, err
+				}
+				id := p.Args["id"].(string)
+				if err := entries.DeleteEntry(id); err != nil {
+					return nil, err
+				}
+				return id, nil
+			}),
+		},
+	},
+})
+
+// graphQLSchema is the schema served at /graphql
+var graphQLSchema = func() graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType, Mutation: mutationType})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}()
+
+// resolveEntries filters, sorts and pages the entries
+func resolveEntries(p graphql.ResolveParams) (any, error) {
+	limit := p.Args["limit"].(int)
+	offset := p.Args["offset"].(int)
+	if limit < 0 || offset < 0 {
+		return nil, errUnprocessable("limit and offset must not be negative")
+	}
+	limit = min(limit, maxPageSize)
+
+	entries, err := graphQLJournal(p)
+	if err != nil {
+		return nil, err
+	}
+	search, _ := p.Args["search"].(string)
+	list, err := entries.SearchEntries(search)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, _ := p.Args["tag"].(string)
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	after, hasAfter := p.Args["createdAfter"].(time.Time)
+	before, hasBefore := p.Args["createdBefore"].(time.Time)
+	list = slices.DeleteFunc(list, func(entry models.Entry) bool {
+		return (tag != "" && !slices.Contains(journal.Tags(entry), tag)) ||
+			(hasAfter && !entry.Created.After(after)) ||
+			(hasBefore && !entry.Created.Before(before))
+	})
+	sortEntries(list, p.Args["sort"].(string))
+
+	page := []v1.Entry{}
+	end := min(offset+limit, len(list))
+	for _, entry := range list[min(offset, len(list)):end] {
+		page = append(page, v1.FromEntry(entry))
+	}
+	return map[string]any{
+		"entries":    page,
+		"totalCount": len(list),
+		"hasMore":    end < len(list),
+	}, nil
+}
+
+// sortEntries orders the entries as named by an EntrySort value, breaking
+// ties by ID so pages are stable
+func sortEntries(entries []models.Entry, order string) {
+	slices.SortFunc(entries, func(a, b models.Entry) int {
+		var c int
+		switch order {
+		case "oldest":
+			c = a.Created.Compare(b.Created)
+		case "updated":
+			c = b.Updated.Compare(a.Updated)
+		case "title":
+			c = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		default:
+			c = b.Created.Compare(a.Created)
+		}
+		if c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
+
+// resolveStats counts the entries by notebook and by tag
+func resolveStats(p graphql.ResolveParams) (any, error) {
+	entries, err := graphQLJournal(p)
+	if err != nil {
+		return nil, err
+	}
+	notebooks := map[string]int{}
+	tags := map[string]int{}
+	stats := map[string]any{"entries": 0}
+	var first, last time.Time
+	err = entries.ForEachEntry(func(entry models.Entry) error {
+		notebooks[entry.Notebook]++
+		for _, tag := range journal.Tags(entry) {
+			tags[tag]++
+		}
+		if first.IsZero() || entry.Created.Before(first) {
+			first = entry.Created
+		}
+		if entry.Created.After(last) {
+			last = entry.Created
+		}
+		stats["entries"] = stats["entries"].(int) + 1
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats["notebooks"] = counts(notebooks)
+	stats["tags"] = counts(tags)
+	if !first.IsZero() {
+		stats["first"] = first
+		stats["last"] = last
+	}
+	return stats, nil
+}
+
+// counts lists the counts of a map as Count objects, the largest first
+func counts(m map[string]int) []map[string]any {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if m[a] != m[b] {
+			return m[b] - m[a]
+		}
+		return strings.Compare(a, b)
+	})
+	list := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		list = append(list, map[string]any{"name": name, "count": m[name]})
+	}
+	return list
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"net"
+)
+
+// grpcConfig holds how the gRPC journal service listens
+type grpcConfig struct {
+	addr     string // host:port; the service isn't served when empty
+	certFile string // TLS certificate; the HTTP server's when empty
+	keyFile  string
+	insecure bool // Whether plaintext is allowed on addresses other than loopback
+}
+
+// grpcFlags registers the flags configuring the gRPC service
+func grpcFlags() *grpcConfig {
+	config := &grpcConfig{}
+	flag.StringVar(&config.addr, "grpc", "", "also serve the gRPC journal service on this address, e.g. :9090")
+	flag.StringVar(&config.certFile, "grpc-tls-cert", "", "serve gRPC over TLS with this certificate file (defaults to -tls-cert)")
+	flag.StringVar(&config.keyFile, "grpc-tls-key", "", "private key of the gRPC TLS certificate (defaults to -tls-key)")
+	flag.BoolVar(&config.insecure, "grpc-insecure", false, "allow plaintext gRPC on addresses other than loopback, e.g. behind a TLS-terminating proxy")
+	return config
+}
+
+// listenGRPC opens the listener of the gRPC service and returns the options
+// securing it with TLS. API tokens are sent with every call, so plaintext is
+// refused unless the service only listens on loopback or -grpc-insecure is set.
+func listenGRPC(config *grpcConfig, server *serverConfig) (net.Listener, []grpc.ServerOption, error) {
+	certFile, keyFile := config.certFile, config.keyFile
+	if certFile == "" && keyFile == "" {
+		certFile, keyFile = server.certFile, server.keyFile
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, nil, errors.New("set both -grpc-tls-cert and -grpc-tls-key to serve gRPC over TLS")
+	}
+	var opts []grpc.ServerOption
+	if certFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	listener, err := net.Listen("tcp", config.addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if certFile == "" && !config.insecure && !listener.Addr().(*net.TCPAddr).IP.IsLoopback() {
+		listener.Close()
+		return nil, nil, fmt.Errorf("refusing plaintext gRPC on %s: set -grpc-tls-cert and -grpc-tls-key, listen on loopback or set -grpc-insecure", config.addr)
+	}
+	return listener, opts, nil
+}
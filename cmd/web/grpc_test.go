@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestListenGRPCRefusesPublicPlaintext(t *testing.T) {
+	tests := []struct {
+		config  grpcConfig
+		allowed bool
+	}{
+		{grpcConfig{addr: "127.0.0.1:0"}, true},
+		{grpcConfig{addr: ":0"}, false},
+		{grpcConfig{addr: ":0", insecure: true}, true},
+		{grpcConfig{addr: ":0", certFile: "cert.pem"}, false}, // Without a key
+	}
+	for _, test := range tests {
+		listener, _, err := listenGRPC(&test.config, &serverConfig{})
+		if err == nil {
+			listener.Close()
+		}
+		if (err == nil) != test.allowed {
+			t.Errorf("%+v: got %v, want allowed %t", test.config, err, test.allowed)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// readyTimeout is how long /readyz waits for the storage to answer
+const readyTimeout = 2 * time.Second
+
+// healthStatus is the body of the health and readiness answers
+type healthStatus struct {
+	Status string `json:"status"`
+}
+
+// HealthHandler answers that the server is up, without checking the storage,
+// so a slow database doesn't get the server restarted.
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// ReadyHandler answers whether the server can take requests: the storage must
+// be reachable and the server not shutting down.
+func ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-streamsDone:
+		writeProblem(w, r, http.StatusServiceUnavailable, "the server is shutting down")
+		return
+	default:
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := journalIntance.Ping(ctx); err != nil {
+		requestLogger(r).Error("Readiness check failed", "err", err)
+		writeProblem(w, r, http.StatusServiceUnavailable, "the storage can't be reached")
+		return
+	}
+	writeJSON(w, http.StatusOK, healthStatus{Status: "ready"})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Environment variables configuring the logs. The flags of the same name
+// override them.
+const (
+	logLevelEnv  = "JOURNAL_LOG_LEVEL"
+	logFormatEnv = "JOURNAL_LOG_FORMAT"
+)
+
+// requestIDHeader carries the ID of a request, from a proxy in front of the
+// server or else made up by it, and is sent back in the response
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID matches the request IDs taken from the header, so clients
+// can't put arbitrary text in the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// quietRoutes are logged at debug level, since probes and scrapes come every
+// few seconds
+var quietRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// loggerKey is the context key of the logger of a request
+type loggerKey struct{}
+
+// logConfig holds how the server logs
+type logConfig struct {
+	level  string // debug, info, warn or error
+	format string // text or json
+}
+
+// logFlags registers the flags configuring the logs, with defaults taken from
+// the environment
+func logFlags() *logConfig {
+	config := &logConfig{}
+	flag.StringVar(&config.level, "log-level", envString(logLevelEnv, "info"), "lowest level logged: debug, info, warn or error (or $"+logLevelEnv+")")
+	flag.StringVar(&config.format, "log-format", envString(logFormatEnv, "text"), "log as text or json (or $"+logFormatEnv+")")
+	return config
+}
+
+// setupLogging makes the configured logger the default, which the log
+// package writes through too
+func setupLogging(config *logConfig) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.level)); err != nil {
+		return fmt.Errorf("invalid log level %q: expected debug, info, warn or error", config.level)
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(config.format) {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("invalid log format %q: expected text or json", config.format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// requestLogger returns the logger of the request, which adds its ID to every
+// record, or the default logger outside of requests
+func requestLogger(r *http.Request) *slog.Logger {
+	return contextLogger(r.Context())
+}
+
+// contextLogger returns the logger stored in ctx, or the default logger
+func contextLogger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// logRequests gives every request an ID and a logger carrying it, and logs the
+// request once answered. Use it as router middleware, so the route is known,
+// and wrap the not found handlers with it too.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		logger := slog.Default().With("request_id", id)
+		r = r.WithContext(context.WithValue(r.Context(), loggerKey{}, logger))
+
+		route := routeTemplate(r)
+		recorder := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		defer func() {
+			level := slog.LevelInfo
+			switch {
+			case recorder.code() >= 500:
+				level = slog.LevelError
+			case quietRoutes[route]:
+				level = slog.LevelDebug
+			}
+			logger.Log(r.Context(), level, "Request",
+				"method", r.Method,
+				"route", route,
+				"path", r.URL.Path,
+				"status", recorder.code(),
+				"bytes", recorder.bytes,
+				"duration", time.Since(start),
+				"remote", r.RemoteAddr,
+			)
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
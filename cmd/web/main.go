@@ -1,28 +1,45 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/gorilla/mux"
 	"html/template"
 	"journal/models"
+	"journal/pkg/api/v1"
+	"journal/pkg/auth"
 	"journal/pkg/journal"
+	"journal/pkg/metrics"
+	"journal/pkg/rpc"
 	"journal/pkg/storage"
 	"journal/pkg/utils"
-	"log"
+	"journal/pkg/webhooks"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
-type EntryInput struct {
-	Title, Content string
-}
-
 type PageData struct {
 	Title         string
 	Entries       []models.Entry
 	Entry         models.Entry
 	ShowCreateBtn bool
 	Error         string
+	Flash         string // One-off message carried over from the previous request
+	FormAction    string // Where the entry form posts to
+	Query         string // Search query the entry list is filtered by
+	Inline        bool   // Whether the entry form is embedded in the list and submitted with htmx
+	User          models.User
+	Next          string // Where to go after logging in
+	Tokens        []models.Token
+	NewToken      string // Secret of a just-created API token, shown only once
 }
 
 var journalIntance *journal.Journal
@@ -30,231 +47,483 @@ var journalIntance *journal.Journal
 // Create a FuncMap with the custom time formatting function
 var funcMap = template.FuncMap{
 	"formatTime": utils.FormatTime,
+	"markdown":   utils.RenderMarkdown,
 }
 
 func main() {
+	dev := flag.Bool("dev", false, "reload templates from ./templates on every request")
+	flag.BoolVar(&insecureCookies, "insecure-cookies", false, "allow session cookies over plain HTTP (for local development)")
+	deliverWebhooks := flag.Bool("webhooks", true, "deliver entry events to users' webhooks (turn off on all but one server sharing a database)")
+	instance := flag.String("instance", instanceName(), "name of this server among those sharing the database (or $"+instanceEnv+")")
+	config := serverFlags()
+	grpcConfig := grpcFlags()
+	dbOptions := databaseFlags()
+	logging := logFlags()
+	flag.Parse()
+	if err := setupLogging(logging); err != nil {
+		fatal("Invalid logging settings", err)
+	}
+
+	// Interrupting or terminating the server shuts it down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	//Initialize storage
 	//db, err := storage.NewSQLiteStorage("journal.db")
-	db, err := storage.NewMongoDBStorage("journal", "entries")
+	db, err := storage.NewMongoDBStorageWithOptions("journal", "entries", *dbOptions)
 	if err != nil {
-		log.Fatal("Failed to initialize storage: ", err)
+		fatal("Failed to initialize storage", err)
 	}
 
-	journalIntance = journal.NewJournal(db)
+	// Entry and notebook operations are timed for /metrics
+	journalIntance = journal.NewJournal(metrics.InstrumentStorage(db, "mongodb"))
+	accountsInstance = auth.NewAccounts(db)
+	if err := metrics.RegisterEntryCounter(db, "mongodb"); err != nil {
+		fatal("Failed to register metrics", err)
+	}
 
-	// Set up router
-	router := mux.NewRouter()
+	// Entry events then include the changes made by other servers
+	go watchStorage(ctx, *instance)
+	webhooksInstance = webhooks.New(db)
+	webhooksStopped := make(chan struct{})
+	if *deliverWebhooks {
+		go func() {
+			webhooksInstance.Run(ctx, journalIntance.Events())
+			close(webhooksStopped)
+		}()
+	} else {
+		close(webhooksStopped)
+	}
 
-	// Define routes
-	router.HandleFunc("/test", TestHandler).Methods("GET")
-	router.HandleFunc("/app", EntriesHandler).Methods("GET")
-	router.HandleFunc("/app/entries/new", NewEntryPageHandler).Methods("GET")
-	router.HandleFunc("/app/entries/new", PostNewEntryHandler).Methods("POST")
-	router.HandleFunc("/app/entries/{id}", ViewEntryHandler).Methods("GET") // Get a specified entry by ID
-	router.HandleFunc("/api/entries", ListEntries).Methods("GET")           // List all entries
-	router.HandleFunc("/api/entries", CreateEntry).Methods("POST")          // Create a new entry
-	router.HandleFunc("/api/entries/{id}", GetEntry).Methods("GET")         // Get a specified entry by ID
-	router.HandleFunc("/api/entries/{id}", UpdateEntry).Methods("PUT")      //  // Update an entry by ID
-	router.HandleFunc("/api/entries/{id}", DeleteEntry).Methods("DELETE")   // Delete an entry by ID
+	pageTemplates, err = newTemplateCache(*dev)
+	if err != nil {
+		fatal("Failed to parse templates", err)
+	}
+
+	router := newRouter()
+
+	rpcStopped := make(chan error, 1)
+	if grpcConfig.addr != "" {
+		listener, opts, err := listenGRPC(grpcConfig, config)
+		if err != nil {
+			fatal("Failed to listen for gRPC", err)
+		}
+		slog.Info("Serving gRPC", "addr", listener.Addr().String(), "tls", len(opts) > 0)
+		go func() {
+			rpcStopped <- rpc.Serve(ctx, listener, journalIntance, accountsInstance, opts...)
+		}()
+	} else {
+		rpcStopped <- nil
+	}
 
 	// Start HTTP server
-	port := ":8080"
-	fmt.Println("Starting server on", port)
-	log.Fatal(http.ListenAndServe(port, router))
+	served := serve(ctx, config, router)
+	if served != nil {
+		slog.Error("Serving HTTP failed", "err", served)
+	}
+	stop()
+	if err := <-rpcStopped; err != nil {
+		slog.Error("Serving gRPC failed", "err", err)
+	}
+	// Deliveries given up on shutdown are recorded as dead letters before the storage closes
+	<-webhooksStopped
+	if err := db.Close(); err != nil {
+		slog.Error("Closing the storage failed", "err", err)
+	}
+	if served != nil {
+		os.Exit(1)
+	}
+}
 
+// fatal logs why the server can't start and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
 
 func TestHandler(w http.ResponseWriter, r *http.Request) {
 
-	templates := template.Must(template.ParseFiles("templates/pages/heloworld.html"))
 	data := "Test"
-	templates.Execute(w, data)
+	pageTemplates.render(w, http.StatusOK, "helloworld", data)
 }
 
 func ViewEntryHandler(w http.ResponseWriter, r *http.Request) {
-	templates := template.Must(template.New("").Funcs(funcMap).ParseFiles("templates/layouts/base.html",
-		"templates/pages/entry.html", "templates/partials/header.html"))
 	data := PageData{
 		Title:         "View Entry",
 		ShowCreateBtn: true,
+		User:          currentUser(r),
 		//Error: "Method not allowed",
 	}
 
 	id := mux.Vars(r)["id"]
-	entry, err := journalIntance.GetEntry(id)
+	entry, err := userJournal(r).GetEntry(id)
+	if errors.Is(err, journal.ErrEntryNotFound) {
+		NotFoundPageHandler(w, r)
+		return
+	}
 	if err != nil {
-		http.Redirect(w, r, "/app", http.StatusSeeOther)
+		requestLogger(r).Error("Fetching entry failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
 	}
 	data.Entry = entry
+	data.Flash = popFlash(w, r)
+
+	pageTemplates.render(w, http.StatusOK, "entry", data)
+}
 
-	err = templates.ExecuteTemplate(w, "base", data)
+// EditEntryPageHandler shows the entry form prefilled with an existing entry
+func EditEntryPageHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	entry, err := userJournal(r).GetEntry(id)
+	if errors.Is(err, journal.ErrEntryNotFound) {
+		NotFoundPageHandler(w, r)
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Fetching entry failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
 
+	data := PageData{
+		Title:         "Edit Entry",
+		Entry:         entry,
+		ShowCreateBtn: true,
+		User:          currentUser(r),
+		FormAction:    "/app/entries/" + entry.ID + "/edit",
+	}
+	pageTemplates.render(w, http.StatusOK, "new", data)
 }
 
-func PostNewEntryHandler(w http.ResponseWriter, r *http.Request) {
-	templates := template.Must(template.ParseFiles("templates/layouts/base.html",
-		"templates/pages/new.html", "templates/partials/form.html", "templates/partials/header.html"))
+// PostEditEntryHandler saves the edited entry and redirects back to it
+func PostEditEntryHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	data := PageData{
+		Title:         "Edit Entry",
+		Entry:         models.Entry{ID: id},
+		ShowCreateBtn: true,
+		User:          currentUser(r),
+		FormAction:    "/app/entries/" + id + "/edit",
+	}
+	if err := r.ParseForm(); err != nil {
+		data.Error = "Invalid input"
+		renderEntryForm(w, r, http.StatusBadRequest, data)
+		return
+	}
+
+	title := r.FormValue("title")
+	content := r.FormValue("content")
+	data.Entry = models.Entry{ID: id, Title: title, Content: content}
+
+	if len(title) < 1 {
+		data.Error = "Title is required!"
+		renderEntryForm(w, r, http.StatusOK, data)
+		return
+	}
+	if len(content) < 1 {
+		data.Error = "Content is required!"
+		renderEntryForm(w, r, http.StatusOK, data)
+		return
+	}
+
+	entry, err := userJournal(r).UpdateEntry(id, title, content)
+	if errors.Is(err, journal.ErrEntryNotFound) && isHTMX(r) {
+		// The entry is gone; swapping in nothing removes it from the list
+		return
+	}
+	if errors.Is(err, journal.ErrEntryNotFound) {
+		setFlash(w, "That entry no longer exists.")
+		http.Redirect(w, r, "/app", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Updating entry failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
+	if isHTMX(r) {
+		pageTemplates.render(w, http.StatusOK, "entry-card", entry)
+		return
+	}
+	setFlash(w, "Entry updated.")
+	http.Redirect(w, r, "/app/entries/"+id, http.StatusSeeOther)
+}
 
+// PostDeleteEntryHandler deletes an entry and redirects to the entry list
+func PostDeleteEntryHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	err := userJournal(r).DeleteEntry(id)
+	if isHTMX(r) && (err == nil || errors.Is(err, journal.ErrEntryNotFound)) {
+		// An empty response removes the entry card from the list
+		return
+	}
+	if errors.Is(err, journal.ErrEntryNotFound) {
+		setFlash(w, "That entry was already deleted.")
+		http.Redirect(w, r, "/app", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Deleting entry failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
+	setFlash(w, "Entry deleted.")
+	http.Redirect(w, r, "/app", http.StatusSeeOther)
+}
+
+// NotFoundPageHandler renders the 404 page
+func NotFoundPageHandler(w http.ResponseWriter, r *http.Request) {
+	data := PageData{
+		Title: "Not Found",
+	}
+	pageTemplates.render(w, http.StatusNotFound, "404", data)
+}
+
+// ServerErrorPageHandler renders the 500 page
+func ServerErrorPageHandler(w http.ResponseWriter, r *http.Request) {
+	data := PageData{
+		Title: "Something went wrong",
+	}
+	pageTemplates.render(w, http.StatusInternalServerError, "500", data)
+}
+
+func PostNewEntryHandler(w http.ResponseWriter, r *http.Request) {
 	data := PageData{
 		Title:         "Add New Entry",
 		ShowCreateBtn: false,
+		User:          currentUser(r),
+		FormAction:    "/app/entries/new",
 		//Error: "Method not allowed",
 	}
 	if r.Method != http.MethodPost {
 		data.Error = "Method not allowed"
-		templates.ExecuteTemplate(w, "base", data)
-
+		renderEntryForm(w, r, http.StatusOK, data)
 		return
 	}
 	if err := r.ParseForm(); err != nil {
 		data.Error = "Invalid input"
-		fmt.Println(err)
-		err = templates.ExecuteTemplate(w, "base", data)
+		renderEntryForm(w, r, http.StatusBadRequest, data)
 		//log.Fatal("Create entry failed...:", err)
 		return
 	}
 
 	title := r.FormValue("title")
 	content := r.FormValue("content")
+	data.Entry = models.Entry{Title: title, Content: content}
 
 	if len(title) < 1 {
 		data.Error = "Title is required!"
-		err := templates.ExecuteTemplate(w, "base", data)
-		fmt.Println(err)
+		renderEntryForm(w, r, http.StatusOK, data)
 		return
 	}
 	if len(content) < 1 {
 		data.Error = "Content is required!"
-		err := templates.ExecuteTemplate(w, "base", data)
-		fmt.Println(err)
+		renderEntryForm(w, r, http.StatusOK, data)
 		return
 	}
-	_, err := journalIntance.CreateEntry(title, content)
+	entry, err := userJournal(r).CreateEntry(title, content)
 	//fmt.Println("ran", entry)
 	if err != nil {
-		http.Error(w, "Failed to create entry", http.StatusInternalServerError)
+		requestLogger(r).Error("Creating entry failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
+	if isHTMX(r) {
+		// Replaces the inline form with nothing and prepends the new card to the list
+		pageTemplates.render(w, http.StatusOK, "entry-created", entry)
+		return
 	}
+	setFlash(w, "Entry created.")
 	http.Redirect(w, r, "/app", http.StatusSeeOther)
 }
 
-func NewEntryPageHandler(w http.ResponseWriter, r *http.Request) {
-	templates := template.Must(template.ParseFiles("templates/layouts/base.html",
-		"templates/pages/new.html", "templates/partials/form.html", "templates/partials/header.html"))
+// PreviewEntryHandler renders the submitted content as Markdown for the new entry form preview tab
+func PreviewEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, utils.RenderMarkdown(r.FormValue("content")))
+}
+
+// HighlightCSSHandler serves the stylesheet for syntax-highlighted code blocks
+func HighlightCSSHandler(w http.ResponseWriter, r *http.Request) {
+	css, err := utils.HighlightCSS()
+	if err != nil {
+		http.Error(w, "Failed to generate stylesheet", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Write(css)
+}
 
+func NewEntryPageHandler(w http.ResponseWriter, r *http.Request) {
 	data := PageData{
 		Title:         "Add New Entry",
 		ShowCreateBtn: false,
+		User:          currentUser(r),
+		FormAction:    "/app/entries/new",
 	}
-	templates.ExecuteTemplate(w, "base", data)
+	pageTemplates.render(w, http.StatusOK, "new", data)
 }
 
 func EntriesHandler(w http.ResponseWriter, r *http.Request) {
-
-	templates := template.Must(template.New("").Funcs(funcMap).ParseFiles("templates/layouts/base.html",
-		"templates/pages/entries.html", "templates/partials/header.html"))
-
-	entries, _ := journalIntance.ListEntries()
+	query := r.URL.Query().Get("q")
+	entries, err := userJournal(r).SearchEntries(query)
+	if err != nil {
+		requestLogger(r).Error("Listing entries failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
 	data := PageData{
 		Title:         "Journal Entries",
 		Entries:       entries,
 		ShowCreateBtn: true,
+		User:          currentUser(r),
+		Flash:         popFlash(w, r),
+		Query:         query,
 	}
 	//fmt.Printf("Home handler %+v\n", data)
-	err := templates.ExecuteTemplate(w, "base", data)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	pageTemplates.render(w, http.StatusOK, "entries", data)
 }
 
 // GetEntry fetches a specific entry by ID
-func GetEntry(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-	entry, err := journalIntance.GetEntry(id)
+func GetEntry(w http.ResponseWriter, r *http.Request) error {
+	entry, err := userJournal(r).GetEntry(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "Entry not found", http.StatusNotFound)
-
-	}
-	err = json.NewEncoder(w).Encode(entry)
-	if err != nil {
-		log.Fatal("Sending get entry response failed:", err)
+		return err
 	}
+	writeJSON(w, http.StatusOK, v1.FromEntry(entry))
+	return nil
 }
 
-// ListEntries list all entries
-func ListEntries(w http.ResponseWriter, r *http.Request) {
-	entries, err := journalIntance.ListEntries()
-
+// ListEntries list all entries, or those matching the q query parameter
+func ListEntries(w http.ResponseWriter, r *http.Request) error {
+	entries, err := userJournal(r).SearchEntries(r.URL.Query().Get("q"))
 	if err != nil {
-		http.Error(w, "Failed to fetch entries", http.StatusInternalServerError)
-		log.Fatal("List entries failed:", err)
+		return err
 	}
+	writeJSON(w, http.StatusOK, v1.FromEntries(entries))
+	return nil
+}
 
-	w.WriteHeader(http.StatusCreated)
-	err = json.NewEncoder(w).Encode(entries)
-	if err != nil {
-		log.Fatal("Sending entries response failed:", err)
+// exportFlushEvery is how many exported entries are buffered before they are sent
+const exportFlushEvery = 100
+
+// ExportEntries streams every entry as newline-delimited JSON, one entry per
+// line, without loading them all in memory.
+func ExportEntries(w http.ResponseWriter, r *http.Request) error {
+	// Errors before the first entry is written still replace this with problem+json
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	controller := http.NewResponseController(w)
+	// Large journals can take longer to export than the write timeout allows
+	controller.SetWriteDeadline(time.Time{})
+	encoder := json.NewEncoder(w)
+	count := 0
+	err := userJournal(r).ForEachEntry(func(entry models.Entry) error {
+		if err := encoder.Encode(v1.FromEntry(entry)); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			return controller.Flush()
+		}
+		return nil
+	})
+	if err != nil && count > 0 {
+		// The status is already sent, so abort the response to let the client
+		// see the export is incomplete rather than a shorter, valid stream.
+		requestLogger(r).Error("Export failed", "entries", count, "err", err)
+		panic(http.ErrAbortHandler)
 	}
-
+	return err
 }
 
 // CreateEntry creates a new journal entry
-func CreateEntry(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+func CreateEntry(w http.ResponseWriter, r *http.Request) error {
+	var entryInput v1.EntryInput
+	if err := decodeJSON(w, r, &entryInput); err != nil {
+		return err
 	}
-
-	var entryInput EntryInput
-	if err := json.NewDecoder(r.Body).Decode(&entryInput); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		log.Fatal("Create entry failed:", err)
-		return
+	entry, err := createEntry(userJournal(r), entryInput)
+	if err != nil {
+		return err
 	}
+	w.Header().Set("Location", "/api/entries/"+entry.ID)
+	writeJSON(w, http.StatusCreated, v1.FromEntry(entry))
+	return nil
+}
 
-	entry, err := journalIntance.CreateEntry(entryInput.Title, entryInput.Content)
-	if err != nil {
-		http.Error(w, "Failed to create entry", http.StatusInternalServerError)
+// createEntry validates the input and creates the entry it describes
+func createEntry(entries *journal.Journal, input v1.EntryInput) (models.Entry, error) {
+	if strings.TrimSpace(input.Title) == "" {
+		return models.Entry{}, errUnprocessable("title is required")
 	}
-	w.WriteHeader(http.StatusCreated)
-	err = json.NewEncoder(w).Encode(entry)
-	if err != nil {
-		log.Fatal("Sending created entry response failed:", err)
+	if strings.TrimSpace(input.Content) == "" {
+		return models.Entry{}, errUnprocessable("content is required")
 	}
-
+	if input.ID != "" {
+		return entries.CreateEntryWithID(input.ID, input.Title, input.Content)
+	}
+	return entries.CreateEntry(input.Title, input.Content)
 }
 
-// UpdateEntry updates an entry by ID
-func UpdateEntry(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-
-	var updateEntryInput EntryInput
-	if err := json.NewDecoder(r.Body).Decode(&updateEntryInput); err != nil {
-		http.Error(w, "Invalid Input", http.StatusBadRequest)
-		return
+// UpdateEntry replaces the title and content of an entry by ID. Fields left
+// out of the body are cleared.
+func UpdateEntry(w http.ResponseWriter, r *http.Request) error {
+	var updateEntryInput v1.EntryInput
+	if err := decodeJSON(w, r, &updateEntryInput); err != nil {
+		return err
+	}
+	if strings.TrimSpace(updateEntryInput.Title) == "" {
+		return errUnprocessable("title is required")
 	}
 
-	entry, err := journalIntance.UpdateEntry(id, updateEntryInput.Title, updateEntryInput.Content)
+	entry, err := userJournal(r).PatchEntry(mux.Vars(r)["id"], journal.UpdateOptions{
+		Title:   &updateEntryInput.Title,
+		Content: &updateEntryInput.Content,
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return err
+	}
+	writeJSON(w, http.StatusOK, v1.FromEntry(entry))
+	return nil
+}
+
+// PatchEntry changes some fields of an entry by ID, following JSON Merge
+// Patch: fields left out are unchanged and null clears a field.
+func PatchEntry(w http.ResponseWriter, r *http.Request) error {
+	var patch v1.EntryPatch
+	if err := decodeJSON(w, r, &patch); err != nil {
+		return err
 	}
-	w.WriteHeader(http.StatusAccepted)
-	err = json.NewEncoder(w).Encode(entry)
+	entry, err := patchEntry(userJournal(r), mux.Vars(r)["id"], patch)
 	if err != nil {
-		log.Fatal("Sending updated entry response failed:", err)
+		return err
 	}
+	writeJSON(w, http.StatusOK, v1.FromEntry(entry))
+	return nil
 }
 
-// DeleteEntry deletes an entry by ID
-func DeleteEntry(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-
-	err := journalIntance.DeleteEntry(id)
-	if err != nil {
-		http.Error(w, "Entry not found", http.StatusNotFound)
-		return
+// patchEntry validates the patch and applies it to the entry
+func patchEntry(entries *journal.Journal, id string, patch v1.EntryPatch) (models.Entry, error) {
+	if patch.Title.Set && strings.TrimSpace(patch.Title.Value) == "" {
+		return models.Entry{}, errUnprocessable("title must not be blank")
 	}
+	return entries.PatchEntry(id, journal.UpdateOptions{
+		Title:   patch.Title.Ptr(),
+		Content: patch.Content.Ptr(),
+	})
+}
 
+// DeleteEntry deletes an entry by ID
+func DeleteEntry(w http.ResponseWriter, r *http.Request) error {
+	if err := userJournal(r).DeleteEntry(mux.Vars(r)["id"]); err != nil {
+		return err
+	}
 	w.WriteHeader(http.StatusNoContent)
-
+	return nil
 }
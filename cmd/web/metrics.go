@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"journal/pkg/metrics"
+	"net/http"
+	"time"
+)
+
+// unmatchedRoute labels the requests no route matched
+const unmatchedRoute = "unmatched"
+
+// statusRecorder remembers the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += n
+	return n, err
+}
+
+// code returns the status code sent, or 200 if nothing was written, which
+// net/http answers with 200
+func (w *statusRecorder) code() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which
+// event streams and exports use to flush and lift the write deadline.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recordMetrics records every request with the pattern of the route it
+// matched. Use it as router middleware, so the route is known, and wrap the
+// not found handlers with it too.
+func recordMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		recorder := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		defer func() {
+			metrics.ObserveRequest(route, r.Method, recorder.code(), time.Since(start))
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// routeTemplate returns the pattern of the route the request matched, such as
+// /api/entries/{id}, or unmatchedRoute
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return unmatchedRoute
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"journal/pkg/api/v1"
+	"net/http"
+	"net/url"
+)
+
+// requireNotebook responds with 404 for routes under a notebook that doesn't exist
+func requireNotebook(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := journalIntance.ForUser(currentUser(r).ID).WithLogger(requestLogger(r)).GetNotebook(mux.Vars(r)["nb"])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ListNotebooks lists the user's notebooks
+func ListNotebooks(w http.ResponseWriter, r *http.Request) error {
+	notebooks, err := userJournal(r).ListNotebooks()
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, v1.FromNotebooks(notebooks))
+	return nil
+}
+
+// CreateNotebook creates a new notebook
+func CreateNotebook(w http.ResponseWriter, r *http.Request) error {
+	var input v1.NotebookInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		return err
+	}
+	notebook, err := userJournal(r).CreateNotebook(input.Name)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", "/api/notebooks/"+url.PathEscape(notebook.Name))
+	writeJSON(w, http.StatusCreated, v1.FromNotebook(notebook))
+	return nil
+}
+
+// GetNotebook fetches a notebook by name
+func GetNotebook(w http.ResponseWriter, r *http.Request) error {
+	notebook, err := userJournal(r).GetNotebook(mux.Vars(r)["nb"])
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, v1.FromNotebook(notebook))
+	return nil
+}
+
+// RenameNotebook renames a notebook
+func RenameNotebook(w http.ResponseWriter, r *http.Request) error {
+	var input v1.NotebookInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		return err
+	}
+	notebook, err := userJournal(r).RenameNotebook(mux.Vars(r)["nb"], input.Name)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, v1.FromNotebook(notebook))
+	return nil
+}
+
+// DeleteNotebook deletes an empty notebook
+func DeleteNotebook(w http.ResponseWriter, r *http.Request) error {
+	if err := userJournal(r).DeleteNotebook(mux.Vars(r)["nb"]); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// MoveEntry files an entry in another notebook
+func MoveEntry(w http.ResponseWriter, r *http.Request) error {
+	var input v1.MoveInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		return err
+	}
+	entry, err := userJournal(r).MoveEntry(mux.Vars(r)["id"], input.Notebook)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, v1.FromEntry(entry))
+	return nil
+}
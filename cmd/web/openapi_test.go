@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"journal/pkg/api/v1"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// undocumentedRoutes are the /api routes left out of the OpenAPI document
+var undocumentedRoutes = map[string]bool{
+	"/api/openapi.json": true, // The document itself
+}
+
+// routerOperations returns the method and path, relative to /api, of every
+// API route the router serves
+func routerOperations(t *testing.T) map[string]bool {
+	t.Helper()
+	operations := map[string]bool{}
+	err := newRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || undocumentedRoutes[path] {
+			return nil
+		}
+		path, ok := strings.CutPrefix(path, "/api/")
+		if !ok {
+			return nil
+		}
+		// Subrouters and prefixes have no methods
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			operations[method+" /"+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking the routes: %v", err)
+	}
+	return operations
+}
+
+// documentOperations returns the method and path of every operation in the
+// OpenAPI document
+func documentOperations(t *testing.T) map[string]bool {
+	t.Helper()
+	var document struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(v1.OpenAPI, &document); err != nil {
+		t.Fatalf("parsing openapi.json: %v", err)
+	}
+	httpMethods := map[string]bool{"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true}
+	operations := map[string]bool{}
+	for path, item := range document.Paths {
+		for key := range item {
+			if httpMethods[key] {
+				operations[strings.ToUpper(key)+" "+path] = true
+			}
+		}
+	}
+	return operations
+}
+
+// missing returns the operations of want that got lacks, sorted
+func missing(want, got map[string]bool) []string {
+	var lacking []string
+	for operation := range want {
+		if !got[operation] {
+			lacking = append(lacking, operation)
+		}
+	}
+	sort.Strings(lacking)
+	return lacking
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	routes := routerOperations(t)
+	if len(routes) == 0 {
+		t.Fatal("found no API routes")
+	}
+	for _, operation := range missing(routes, documentOperations(t)) {
+		t.Errorf("%s is served but not in openapi.json", operation)
+	}
+}
+
+func TestOpenAPIOnlyDocumentsServedRoutes(t *testing.T) {
+	for _, operation := range missing(documentOperations(t), routerOperations(t)) {
+		t.Errorf("%s is in openapi.json but not served", operation)
+	}
+}
+
+func TestOpenAPIDocumentsEveryProblemType(t *testing.T) {
+	var document struct {
+		Components struct {
+			Schemas struct {
+				Problem struct {
+					Properties struct {
+						Type struct {
+							Enum []string `json:"enum"`
+						} `json:"type"`
+					} `json:"properties"`
+				} `json:"Problem"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(v1.OpenAPI, &document); err != nil {
+		t.Fatalf("parsing openapi.json: %v", err)
+	}
+	documented := map[string]bool{}
+	for _, problemType := range document.Components.Schemas.Problem.Properties.Type.Enum {
+		documented[problemType] = true
+	}
+	for _, known := range problemTypes {
+		if !documented[known.problemType] {
+			t.Errorf("problem type %s of %q is not in openapi.json", known.problemType, known.err)
+		}
+	}
+}
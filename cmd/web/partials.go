@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"journal/pkg/journal"
+	"net/http"
+)
+
+// isHTMX reports whether the request was issued by htmx and expects a fragment
+func isHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// renderEntryForm re-renders the entry form, as a fragment for htmx requests
+// and as the full page otherwise
+func renderEntryForm(w http.ResponseWriter, r *http.Request, status int, data PageData) {
+	if isHTMX(r) {
+		data.Inline = true
+		pageTemplates.render(w, status, "entry-form", data)
+		return
+	}
+	pageTemplates.render(w, status, "new", data)
+}
+
+// EntryListPartialHandler renders the entry list, filtered by the optional q query parameter
+func EntryListPartialHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	entries, err := userJournal(r).SearchEntries(query)
+	if err != nil {
+		requestLogger(r).Error("Listing entries failed", "err", err)
+		http.Error(w, "Failed to fetch entries", http.StatusInternalServerError)
+		return
+	}
+	data := PageData{
+		Entries: entries,
+		Query:   query,
+	}
+	pageTemplates.render(w, http.StatusOK, "entry-list", data)
+}
+
+// NewEntryFormPartialHandler renders an empty entry form to embed in the entry list
+func NewEntryFormPartialHandler(w http.ResponseWriter, r *http.Request) {
+	data := PageData{
+		FormAction: "/app/entries/new",
+		Inline:     true,
+	}
+	pageTemplates.render(w, http.StatusOK, "entry-form", data)
+}
+
+// EntryCardPartialHandler renders a single entry card
+func EntryCardPartialHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	entry, err := userJournal(r).GetEntry(id)
+	if errors.Is(err, journal.ErrEntryNotFound) {
+		// Swapping in nothing removes the stale card
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Fetching entry failed", "err", err)
+		http.Error(w, "Failed to fetch entry", http.StatusInternalServerError)
+		return
+	}
+	pageTemplates.render(w, http.StatusOK, "entry-card", entry)
+}
+
+// EditEntryFormPartialHandler renders the entry form prefilled with an existing entry,
+// replacing its card in the list
+func EditEntryFormPartialHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	entry, err := userJournal(r).GetEntry(id)
+	if errors.Is(err, journal.ErrEntryNotFound) {
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Fetching entry failed", "err", err)
+		http.Error(w, "Failed to fetch entry", http.StatusInternalServerError)
+		return
+	}
+	data := PageData{
+		Entry:      entry,
+		FormAction: "/app/entries/" + entry.ID + "/edit",
+		Inline:     true,
+	}
+	pageTemplates.render(w, http.StatusOK, "entry-form", data)
+}
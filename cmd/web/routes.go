@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"journal/pkg/metrics"
+)
+
+// newRouter registers the routes of the pages, the API and monitoring
+func newRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(logRequests, recordMetrics)
+
+	// Probes and metrics for monitoring, reachable without logging in
+	router.HandleFunc("/healthz", HealthHandler).Methods("GET")
+	router.HandleFunc("/readyz", ReadyHandler).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Define routes
+	router.HandleFunc("/test", TestHandler).Methods("GET")
+
+	// Pages reachable without logging in
+	router.HandleFunc("/app/login", LoginPageHandler).Methods("GET")
+	router.HandleFunc("/app/login", PostLoginHandler).Methods("POST")
+	router.HandleFunc("/app/signup", SignupPageHandler).Methods("GET")
+	router.HandleFunc("/app/signup", PostSignupHandler).Methods("POST")
+	router.HandleFunc("/app/logout", PostLogoutHandler).Methods("POST")
+	router.HandleFunc("/app/highlight.css", HighlightCSSHandler).Methods("GET")
+
+	// Pages for logged-in users, showing only their own entries
+	app := router.PathPrefix("/app").Subrouter()
+	app.Use(requireUser)
+	app.HandleFunc("", EntriesHandler).Methods("GET")
+	app.HandleFunc("/entries/new", NewEntryPageHandler).Methods("GET")
+	app.HandleFunc("/entries/new", PostNewEntryHandler).Methods("POST")
+	app.HandleFunc("/entries/preview", PreviewEntryHandler).Methods("POST")
+	app.HandleFunc("/entries/{id}", ViewEntryHandler).Methods("GET") // Get a specified entry by ID
+	app.HandleFunc("/entries/{id}/edit", EditEntryPageHandler).Methods("GET")
+	app.HandleFunc("/entries/{id}/edit", PostEditEntryHandler).Methods("POST")
+	app.HandleFunc("/entries/{id}/delete", PostDeleteEntryHandler).Methods("POST")
+	app.HandleFunc("/partials/entries", EntryListPartialHandler).Methods("GET")
+	app.HandleFunc("/partials/entries/new", NewEntryFormPartialHandler).Methods("GET")
+	app.HandleFunc("/partials/entries/{id}", EntryCardPartialHandler).Methods("GET")
+	app.HandleFunc("/partials/entries/{id}/edit", EditEntryFormPartialHandler).Methods("GET")
+	app.HandleFunc("/tokens", TokensPageHandler).Methods("GET")
+	app.HandleFunc("/tokens", PostTokenHandler).Methods("POST")
+	app.HandleFunc("/tokens/{id}/revoke", PostRevokeTokenHandler).Methods("POST")
+
+	// The API description is public, so it is registered ahead of the
+	// authenticated /api subrouter.
+	router.HandleFunc("/api/openapi.json", OpenAPIHandler).Methods("GET")
+
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(requireAPIUser)
+	api.Handle("/entries", apiHandler(ListEntries)).Methods("GET")          // List all entries
+	api.Handle("/entries", apiHandler(CreateEntry)).Methods("POST")         // Create a new entry
+	api.Handle("/entries/export", apiHandler(ExportEntries)).Methods("GET") // Stream every entry as NDJSON
+	api.Handle("/entries/{id}", apiHandler(GetEntry)).Methods("GET")        // Get a specified entry by ID
+	api.Handle("/entries/{id}", apiHandler(UpdateEntry)).Methods("PUT")     // Replace an entry by ID
+	api.Handle("/entries/{id}", apiHandler(PatchEntry)).Methods("PATCH")    // Change some fields of an entry by ID
+	api.Handle("/entries/{id}", apiHandler(DeleteEntry)).Methods("DELETE")  // Delete an entry by ID
+	api.Handle("/entries:batch", apiHandler(BatchEntries)).Methods("POST")
+	api.Handle("/entries/{id}/move", apiHandler(MoveEntry)).Methods("POST")
+	api.Handle("/events", apiHandler(Events)).Methods("GET") // Stream changes to entries as server-sent events
+	api.Handle("/notebooks", apiHandler(ListNotebooks)).Methods("GET")
+	api.Handle("/notebooks", apiHandler(CreateNotebook)).Methods("POST")
+	api.Handle("/notebooks/{nb}", apiHandler(GetNotebook)).Methods("GET")
+	api.Handle("/notebooks/{nb}", apiHandler(RenameNotebook)).Methods("PUT")
+	api.Handle("/notebooks/{nb}", apiHandler(DeleteNotebook)).Methods("DELETE")
+	api.Handle("/webhooks", apiHandler(ListWebhooks)).Methods("GET")
+	api.Handle("/webhooks", apiHandler(CreateWebhook)).Methods("POST")
+	api.Handle("/webhooks/dead-letters", apiHandler(ListDeadLetters)).Methods("GET") // Deliveries that failed every attempt
+	api.Handle("/webhooks/{id}", apiHandler(GetWebhook)).Methods("GET")
+	api.Handle("/webhooks/{id}", apiHandler(DeleteWebhook)).Methods("DELETE")
+	api.Handle("/webhooks/{id}/test", apiHandler(TestWebhook)).Methods("POST") // Send a test event
+
+	// The entry routes again, limited to the entries of a single notebook
+	api.Handle("/notebooks/{nb}/entries", requireNotebook(apiHandler(ListEntries))).Methods("GET")
+	api.Handle("/notebooks/{nb}/entries", requireNotebook(apiHandler(CreateEntry))).Methods("POST")
+	api.Handle("/notebooks/{nb}/entries:batch", requireNotebook(apiHandler(BatchEntries))).Methods("POST")
+	api.Handle("/notebooks/{nb}/entries/export", requireNotebook(apiHandler(ExportEntries))).Methods("GET")
+	api.Handle("/notebooks/{nb}/entries/{id}", requireNotebook(apiHandler(GetEntry))).Methods("GET")
+	api.Handle("/notebooks/{nb}/entries/{id}", requireNotebook(apiHandler(UpdateEntry))).Methods("PUT")
+	api.Handle("/notebooks/{nb}/entries/{id}", requireNotebook(apiHandler(PatchEntry))).Methods("PATCH")
+	api.Handle("/notebooks/{nb}/entries/{id}", requireNotebook(apiHandler(DeleteEntry))).Methods("DELETE")
+
+	// GraphQL for clients that want to pick the fields and combine queries
+	router.Handle("/graphql", requireGraphQLUser(apiHandler(GraphQLHandler))).Methods("GET", "POST")
+
+	router.NotFoundHandler = logRequests(recordMetrics(notFoundHandler(router)))
+	router.MethodNotAllowedHandler = logRequests(recordMetrics(methodNotAllowedHandler(router)))
+
+	return router
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Environment variables configuring the HTTP server. The flags of the same
+// name override them.
+const (
+	addrEnv            = "JOURNAL_ADDR"
+	tlsCertEnv         = "JOURNAL_TLS_CERT"
+	tlsKeyEnv          = "JOURNAL_TLS_KEY"
+	readTimeoutEnv     = "JOURNAL_READ_TIMEOUT"
+	writeTimeoutEnv    = "JOURNAL_WRITE_TIMEOUT"
+	idleTimeoutEnv     = "JOURNAL_IDLE_TIMEOUT"
+	shutdownTimeoutEnv = "JOURNAL_SHUTDOWN_TIMEOUT"
+)
+
+// unixPrefix marks a listen address as the path of a unix socket
+const unixPrefix = "unix:"
+
+// serverConfig holds how the HTTP server listens and how long it waits
+type serverConfig struct {
+	addr     string // host:port, or unix:/path/to/socket
+	certFile string // TLS certificate; plain HTTP when empty
+	keyFile  string
+
+	readTimeout     time.Duration // Reading a whole request, body included
+	writeTimeout    time.Duration // Writing a response; event streams and exports are exempt
+	idleTimeout     time.Duration // Keeping an idle connection open
+	shutdownTimeout time.Duration // Waiting for requests in progress when shutting down
+}
+
+// serverFlags registers the flags configuring the HTTP server, with defaults
+// taken from the environment
+func serverFlags() *serverConfig {
+	config := &serverConfig{}
+	flag.StringVar(&config.addr, "addr", envString(addrEnv, ":8080"), "address to listen on, or unix:/path for a unix socket (or $"+addrEnv+")")
+	flag.StringVar(&config.certFile, "tls-cert", os.Getenv(tlsCertEnv), "serve HTTPS with this certificate file (or $"+tlsCertEnv+")")
+	flag.StringVar(&config.keyFile, "tls-key", os.Getenv(tlsKeyEnv), "private key of the TLS certificate (or $"+tlsKeyEnv+")")
+	flag.DurationVar(&config.readTimeout, "read-timeout", envDuration(readTimeoutEnv, 15*time.Second), "how long reading a request may take (or $"+readTimeoutEnv+")")
+	flag.DurationVar(&config.writeTimeout, "write-timeout", envDuration(writeTimeoutEnv, 30*time.Second), "how long writing a response may take (or $"+writeTimeoutEnv+")")
+	flag.DurationVar(&config.idleTimeout, "idle-timeout", envDuration(idleTimeoutEnv, 2*time.Minute), "how long to keep idle connections open (or $"+idleTimeoutEnv+")")
+	flag.DurationVar(&config.shutdownTimeout, "shutdown-timeout", envDuration(shutdownTimeoutEnv, 30*time.Second), "how long to wait for requests in progress when shutting down (or $"+shutdownTimeoutEnv+")")
+	return config
+}
+
+// envString returns the environment variable, or def when it isn't set
+func envString(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// envDuration returns the duration set in the environment variable, or def
+// when it isn't set or isn't a duration
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("Ignoring invalid setting", "name", name, "value", value, "err", err)
+		return def
+	}
+	return duration
+}
+
+// listen opens the listener for a host:port address or a unix:/path socket
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	// A socket left behind by a server that didn't shut down cleanly would
+	// make listening fail, but never remove anything else found at the path
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// serve serves the handler as configured until ctx is done, then stops taking
+// new connections and waits up to the shutdown timeout for the requests in
+// progress to finish.
+func serve(ctx context.Context, config *serverConfig, handler http.Handler) error {
+	if (config.certFile == "") != (config.keyFile == "") {
+		return errors.New("set both -tls-cert and -tls-key to serve HTTPS")
+	}
+	listener, err := listen(config.addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: config.readTimeout,
+		ReadTimeout:       config.readTimeout,
+		WriteTimeout:      config.writeTimeout,
+		IdleTimeout:       config.idleTimeout,
+	}
+	// Event streams never finish by themselves, so end them instead of
+	// waiting for them until the timeout
+	server.RegisterOnShutdown(func() { close(streamsDone) })
+
+	served := make(chan error, 1)
+	go func() {
+		if config.certFile != "" {
+			slog.Info("Starting server", "addr", config.addr, "tls", true)
+			served <- server.ServeTLS(listener, config.certFile, config.keyFile)
+		} else {
+			slog.Info("Starting server", "addr", config.addr)
+			served <- server.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	slog.Info("Shutting down, waiting for requests in progress", "timeout", config.shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return fmt.Errorf("requests still in progress after %s: %w", config.shutdownTimeout, err)
+	}
+	return nil
+}
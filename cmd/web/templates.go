@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"journal/templates"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// page describes the template files a page is built from.
+type page struct {
+	files  []string // Paths relative to the templates directory
+	layout string   // Name of the template to execute
+}
+
+// pages lists every page the web app renders, keyed by name.
+var pages = map[string]page{
+	"helloworld": {files: []string{"pages/heloworld.html"}, layout: "heloworld.html"},
+	"entries":    {files: []string{"layouts/base.html", "pages/entries.html", "partials/header.html", "partials/entry-list.html", "partials/entry.html"}, layout: "base"},
+	"entry":      {files: []string{"layouts/base.html", "pages/entry.html", "partials/header.html"}, layout: "base"},
+	"new":        {files: []string{"layouts/base.html", "pages/new.html", "partials/form.html", "partials/header.html"}, layout: "base"},
+	"login":      {files: []string{"layouts/base.html", "pages/login.html", "partials/header.html"}, layout: "base"},
+	"signup":     {files: []string{"layouts/base.html", "pages/signup.html", "partials/header.html"}, layout: "base"},
+	"tokens":     {files: []string{"layouts/base.html", "pages/tokens.html", "partials/header.html"}, layout: "base"},
+	"404":        {files: []string{"layouts/base.html", "pages/404.html"}, layout: "base"},
+	"500":        {files: []string{"layouts/base.html", "pages/500.html"}, layout: "base"},
+
+	// Fragments swapped into the page by htmx
+	"entry-card":    {files: []string{"partials/entry.html"}, layout: "entry-card"},
+	"entry-list":    {files: []string{"partials/entry-list.html", "partials/entry.html"}, layout: "entry-list"},
+	"entry-created": {files: []string{"partials/entry-list.html", "partials/entry.html"}, layout: "entry-created"},
+	"entry-form":    {files: []string{"partials/form.html"}, layout: "form"},
+}
+
+// templateCache holds the parsed page templates.
+type templateCache struct {
+	fsys  fs.FS
+	pages map[string]*template.Template
+	dev   bool // Re-parse templates from fsys on every render
+}
+
+var pageTemplates *templateCache
+
+// newTemplateCache parses every page once. In dev mode the templates are read
+// from the templates directory on disk and re-parsed on each render so edits
+// show up without restarting the server.
+func newTemplateCache(dev bool) (*templateCache, error) {
+	cache := &templateCache{fsys: templates.FS, dev: dev}
+	if dev {
+		cache.fsys = os.DirFS("templates")
+	}
+	parsed := make(map[string]*template.Template, len(pages))
+	for name := range pages {
+		tmpl, err := cache.parse(name)
+		if err != nil {
+			return nil, err
+		}
+		parsed[name] = tmpl
+	}
+	cache.pages = parsed
+	return cache, nil
+}
+
+func (cache *templateCache) parse(name string) (*template.Template, error) {
+	p, ok := pages[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown page %q", name)
+	}
+	tmpl, err := template.New(name).Funcs(funcMap).ParseFS(cache.fsys, p.files...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing page %q: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func (cache *templateCache) lookup(name string) (*template.Template, error) {
+	if cache.dev {
+		return cache.parse(name)
+	}
+	tmpl, ok := cache.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown page %q", name)
+	}
+	return tmpl, nil
+}
+
+// render executes the named page and writes it with the given status code.
+// The page is rendered to a buffer first so a template error results in the
+// 500 page rather than a half-written response.
+func (cache *templateCache) render(w http.ResponseWriter, status int, name string, data any) {
+	var buf bytes.Buffer
+	err := cache.execute(&buf, name, data)
+	if err != nil {
+		slog.Error("Rendering page failed", "page", name, "err", err)
+		if name == "500" {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		cache.render(w, http.StatusInternalServerError, "500", PageData{Title: "Something went wrong"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+func (cache *templateCache) execute(buf *bytes.Buffer, name string, data any) error {
+	tmpl, err := cache.lookup(name)
+	if err != nil {
+		return err
+	}
+	return tmpl.ExecuteTemplate(buf, pages[name].layout, data)
+}
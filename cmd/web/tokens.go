@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"journal/models"
+	"journal/pkg/auth"
+	"net/http"
+)
+
+// renderTokensPage lists the user's API tokens. A newly created token's
+// secret is passed in so it can be shown once.
+func renderTokensPage(w http.ResponseWriter, r *http.Request, status int, data PageData) {
+	user := currentUser(r)
+	tokens, err := accountsInstance.ListTokens(user.ID)
+	if err != nil {
+		requestLogger(r).Error("Listing API tokens failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
+	data.Title = "API Tokens"
+	data.User = user
+	data.Tokens = tokens
+	pageTemplates.render(w, status, "tokens", data)
+}
+
+// TokensPageHandler shows the user's API tokens and a form to create one
+func TokensPageHandler(w http.ResponseWriter, r *http.Request) {
+	renderTokensPage(w, r, http.StatusOK, PageData{Flash: popFlash(w, r)})
+}
+
+// PostTokenHandler creates an API token and shows its secret once
+func PostTokenHandler(w http.ResponseWriter, r *http.Request) {
+	scope := models.TokenScope(r.FormValue("scope"))
+	secret, _, err := accountsInstance.CreateToken(currentUser(r).ID, r.FormValue("name"), scope)
+	if errors.Is(err, auth.ErrTokenNameRequired) || errors.Is(err, auth.ErrInvalidScope) {
+		renderTokensPage(w, r, http.StatusBadRequest, PageData{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Creating API token failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
+	renderTokensPage(w, r, http.StatusCreated, PageData{NewToken: secret})
+}
+
+// PostRevokeTokenHandler deletes one of the user's API tokens
+func PostRevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	err := accountsInstance.RevokeToken(currentUser(r).ID, mux.Vars(r)["id"])
+	if errors.Is(err, auth.ErrTokenNotFound) {
+		setFlash(w, "That token was already revoked.")
+		http.Redirect(w, r, "/app/tokens", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		requestLogger(r).Error("Revoking API token failed", "err", err)
+		ServerErrorPageHandler(w, r)
+		return
+	}
+	setFlash(w, "Token revoked.")
+	http.Redirect(w, r, "/app/tokens", http.StatusSeeOther)
+}
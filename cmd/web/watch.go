@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"journal/pkg/journal"
+	"log/slog"
+	"os"
+)
+
+// instanceEnv names this server among the servers sharing a database
+const instanceEnv = "JOURNAL_INSTANCE"
+
+// instanceName returns $JOURNAL_INSTANCE, or the host name when it isn't set
+func instanceName() string {
+	if name := os.Getenv(instanceEnv); name != "" {
+		return name
+	}
+	name, err := os.Hostname()
+	if err != nil {
+		return "web"
+	}
+	return name
+}
+
+// watchStorage publishes the entry changes the storage reports, including
+// those made by other servers, for as long as ctx lasts. Storage that can't be
+// watched keeps publishing the changes made through this server only.
+func watchStorage(ctx context.Context, name string) {
+	err := journalIntance.Watch(ctx, name)
+	switch {
+	case errors.Is(err, journal.ErrWatchUnsupported):
+		slog.Info("Only changes made through this server are published", "reason", err)
+	case err != nil:
+		slog.Error("Watching the storage for changes failed", "err", err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"journal/pkg/api/v1"
+	"journal/pkg/webhooks"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhookTestTimeout is how long a test delivery waits for the receiver
+const webhookTestTimeout = 15 * time.Second
+
+var webhooksInstance *webhooks.Webhooks
+
+// ListWebhooks lists the user's webhooks, without their secrets
+func ListWebhooks(w http.ResponseWriter, r *http.Request) error {
+	list, err := webhooksInstance.List(currentUser(r).ID)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, v1.FromWebhooks(list))
+	return nil
+}
+
+// CreateWebhook registers a webhook; its secret is only sent in this response
+func CreateWebhook(w http.ResponseWriter, r *http.Request) error {
+	var input v1.WebhookInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		return err
+	}
+	webhook, err := webhooksInstance.Create(currentUser(r).ID, input.URL, input.Events)
+	if err != nil {
+		return err
+	}
+	created := v1.FromWebhook(webhook)
+	created.Secret = webhook.Secret
+	w.Header().Set("Location", "/api/webhooks/"+url.PathEscape(webhook.ID))
+	writeJSON(w, http.StatusCreated, created)
+	return nil
+}
+
+// GetWebhook fetches one of the user's webhooks by ID
+func GetWebhook(w http.ResponseWriter, r *http.Request) error {
+	webhook, err := webhooksInstance.Get(currentUser(r).ID, mux.Vars(r)["id"])
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, v1.FromWebhook(webhook))
+	return nil
+}
+
+// DeleteWebhook removes one of the user's webhooks
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) error {
+	if err := webhooksInstance.Delete(currentUser(r).ID, mux.Vars(r)["id"]); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// TestWebhook sends a test event to one of the user's webhooks. A receiver
+// that can't be reached or answers with an error is reported as 502; why is
+// only logged, so the API can't be used to probe what the server can reach.
+func TestWebhook(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), webhookTestTimeout)
+	defer cancel()
+	id := mux.Vars(r)["id"]
+	if _, err := webhooksInstance.Get(currentUser(r).ID, id); err != nil {
+		return err
+	}
+	if err := webhooksInstance.Test(ctx, currentUser(r).ID, id); err != nil {
+		requestLogger(r).Warn("Test delivery failed", "webhook", id, "err", err)
+		return &apiError{Status: http.StatusBadGateway, Detail: "the receiver couldn't be reached or didn't accept the test event", Err: err}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// ListDeadLetters lists the deliveries to the user's webhooks that failed
+// every attempt, newest first
+func ListDeadLetters(w http.ResponseWriter, r *http.Request) error {
+	letters, err := webhooksInstance.DeadLetters(currentUser(r).ID)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, v1.FromDeadLetters(letters))
+	return nil
+}
@@ -2,13 +2,23 @@ package models
 
 import "time"
 
-// Entry represent a single journal entry
+// Entry represent a single journal entry. The bson tags fix the shape of
+// entry documents in MongoDB; the REST API sends entries as api/v1.Entry.
 type Entry struct {
-	ID      string    // Unique identifier for the entry
-	Title   string    // Title of the journal entry
-	Content string    // Content or body of the journal entry
-	Created time.Time // Timestamp of when the entry was created
-	Updated time.Time // Timestamp of when the entry was last updated
+	ID       string    `bson:"id"`       // Unique identifier for the entry
+	Owner    string    `bson:"owner"`    // ID of the user who owns the entry
+	Notebook string    `bson:"notebook"` // Name of the notebook the entry is filed in; empty if it isn't in one
+	Title    string    `bson:"title"`    // Title of the journal entry
+	Content  string    `bson:"content"`  // Content or body of the journal entry
+	Created  time.Time `bson:"created"`  // Timestamp of when the entry was created
+	Updated  time.Time `bson:"updated"`  // Timestamp of when the entry was last updated
+}
+
+// EntryUpdate lists the fields of an entry to change. Nil fields are left as
+// they are, so a field can be set to empty.
+type EntryUpdate struct {
+	Title   *string
+	Content *string
 }
 
 // UpdateEntry allows you to update the content and title of an existing entry.
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Notebook represent a named journal, such as "work" or "dream log", that entries are filed in
+type Notebook struct {
+	ID      string    `bson:"id"`      // Unique identifier for the notebook
+	Owner   string    `bson:"owner"`   // ID of the user who owns the notebook
+	Name    string    `bson:"name"`    // Name of the notebook, unique per owner
+	Created time.Time `bson:"created"` // Timestamp of when the notebook was created
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// TokenScope is what an API token is allowed to do
+type TokenScope string
+
+const (
+	TokenScopeRead      TokenScope = "read"       // Only read entries
+	TokenScopeReadWrite TokenScope = "read-write" // Read, create, update and delete entries
+)
+
+// Token represent a personal API token used by scripts to call the REST API
+type Token struct {
+	ID       string     `bson:"id"`       // Unique identifier for the token
+	UserID   string     `bson:"userid"`   // ID of the user the token acts as
+	Name     string     `bson:"name"`     // Label to recognise the token by
+	Hash     string     `bson:"hash"`     // SHA-256 hash of the secret token
+	Scope    TokenScope `bson:"scope"`    // What the token is allowed to do
+	Created  time.Time  `bson:"created"`  // Timestamp of when the token was created
+	LastUsed time.Time  `bson:"lastused"` // Timestamp of when the token was last used; zero if never
+}
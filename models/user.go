@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// User represent an account that owns journal entries
+type User struct {
+	ID           string    `bson:"id"`           // Unique identifier for the user
+	Username     string    `bson:"username"`     // Unique name the user logs in with
+	PasswordHash string    `bson:"passwordhash"` // bcrypt hash of the user's password
+	Created      time.Time `bson:"created"`      // Timestamp of when the account was created
+}
+
+// Session represent a logged-in browser session
+type Session struct {
+	TokenHash string    `bson:"tokenhash"` // SHA-256 hash of the token stored in the session cookie
+	UserID    string    `bson:"userid"`    // ID of the user the session belongs to
+	Created   time.Time `bson:"created"`   // Timestamp of when the session was started
+	Expires   time.Time `bson:"expires"`   // Timestamp after which the session is no longer valid
+}
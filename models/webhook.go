@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Webhook represent a URL that is sent the changes to a user's entries
+type Webhook struct {
+	ID      string    `bson:"id"`      // Unique identifier for the webhook
+	Owner   string    `bson:"owner"`   // ID of the user whose entries the webhook hears about
+	URL     string    `bson:"url"`     // Where the events are posted to
+	Events  []string  `bson:"events"`  // Event types sent, such as "created"; empty for every type
+	Secret  string    `bson:"secret"`  // Key the deliveries are signed with
+	Created time.Time `bson:"created"` // Timestamp of when the webhook was created
+}
+
+// DeadLetter represent a webhook delivery that failed every attempt
+type DeadLetter struct {
+	ID        string    `bson:"id"`        // Unique identifier of the delivery
+	WebhookID string    `bson:"webhookid"` // ID of the webhook the event was sent to
+	Owner     string    `bson:"owner"`     // ID of the user owning the webhook
+	URL       string    `bson:"url"`       // Where the event was sent, kept in case the webhook is deleted
+	Event     string    `bson:"event"`     // Type of the event
+	Payload   string    `bson:"payload"`   // JSON body that was sent
+	Attempts  int       `bson:"attempts"`  // How often delivery was tried
+	Error     string    `bson:"error"`     // Why the last attempt failed
+	Created   time.Time `bson:"created"`   // Timestamp of when delivery was given up
+}
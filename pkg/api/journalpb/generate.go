@@ -0,0 +1,5 @@
+// Package journalpb holds the protobuf messages and gRPC stubs of the journal
+// service, generated from journal.proto.
+package journalpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative journal.proto
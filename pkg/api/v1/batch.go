@@ -0,0 +1,52 @@
+package v1
+
+import "encoding/json"
+
+// Operations a batch request can hold
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchRequest is the body of a request running several entry operations at once
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation creates, updates or deletes a single entry. Updates follow
+// the same merge-patch rules as PATCH: fields left out are unchanged.
+type BatchOperation struct {
+	Op      string           `json:"op"`           // BatchCreate, BatchUpdate or BatchDelete
+	ID      string           `json:"id,omitempty"` // Entry to update or delete; optional ID for a created entry
+	Title   Optional[string] `json:"title"`
+	Content Optional[string] `json:"content"`
+}
+
+// MarshalJSON leaves out the fields that aren't set, so an update only
+// changes the fields the caller set.
+func (op BatchOperation) MarshalJSON() ([]byte, error) {
+	fields := map[string]any{"op": op.Op}
+	if op.ID != "" {
+		fields["id"] = op.ID
+	}
+	if op.Title.Set {
+		fields["title"] = op.Title
+	}
+	if op.Content.Set {
+		fields["content"] = op.Content
+	}
+	return json.Marshal(fields)
+}
+
+// BatchResponse lists the result of every operation of a batch, in order
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// BatchResult is the outcome of a single operation
+type BatchResult struct {
+	Status int      `json:"status"`          // HTTP status the operation would have had as a request of its own
+	Entry  *Entry   `json:"entry,omitempty"` // The created or updated entry
+	Error  *Problem `json:"error,omitempty"` // Why the operation failed
+}
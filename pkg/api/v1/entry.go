@@ -0,0 +1,73 @@
+// Package v1 defines the JSON wire format of version 1 of the REST API. The
+// types are kept separate from the models so storage changes can't silently
+// change what clients receive; fields are only ever added to them.
+package v1
+
+import (
+	"journal/models"
+	"time"
+)
+
+// Entry is a journal entry as sent by the API
+type Entry struct {
+	ID       string    `json:"id"`       // Unique identifier for the entry
+	Notebook string    `json:"notebook"` // Name of the notebook the entry is filed in; empty if it isn't in one
+	Title    string    `json:"title"`    // Title of the entry
+	Content  string    `json:"content"`  // Markdown body of the entry
+	Created  time.Time `json:"created"`  // RFC 3339 timestamp of when the entry was created
+	Updated  time.Time `json:"updated"`  // RFC 3339 timestamp of when the entry was last updated
+}
+
+// EntryInput is the body of requests creating or updating an entry
+type EntryInput struct {
+	ID      string `json:"id,omitempty"` // ID for a new entry chosen by the client; the server generates one if empty. Ignored on update
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// MoveInput is the body of requests moving an entry to another notebook
+type MoveInput struct {
+	Notebook string `json:"notebook"` // Empty to take the entry out of its notebook
+}
+
+// FromEntry converts an entry to its wire format
+func FromEntry(entry models.Entry) Entry {
+	return Entry{
+		ID:       entry.ID,
+		Notebook: entry.Notebook,
+		Title:    entry.Title,
+		Content:  entry.Content,
+		Created:  entry.Created,
+		Updated:  entry.Updated,
+	}
+}
+
+// ToEntry converts an entry received from the API back to the model. The
+// owner isn't part of the wire format and is left empty.
+func ToEntry(entry Entry) models.Entry {
+	return models.Entry{
+		ID:       entry.ID,
+		Notebook: entry.Notebook,
+		Title:    entry.Title,
+		Content:  entry.Content,
+		Created:  entry.Created,
+		Updated:  entry.Updated,
+	}
+}
+
+// FromEntries converts a list of entries, returning an empty rather than nil
+// slice so it is encoded as [].
+func FromEntries(entries []models.Entry) []Entry {
+	out := make([]Entry, len(entries))
+	for i, entry := range entries {
+		out[i] = FromEntry(entry)
+	}
+	return out
+}
+
+// EntryPatch is the body of a JSON Merge Patch (RFC 7396) request changing
+// some fields of an entry. Fields left out are not changed; null clears them.
+type EntryPatch struct {
+	Title   Optional[string] `json:"title"`
+	Content Optional[string] `json:"content"`
+}
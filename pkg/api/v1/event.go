@@ -0,0 +1,32 @@
+package v1
+
+import (
+	"journal/models"
+	"time"
+)
+
+// Event is a change to an entry, sent as the data of a server-sent event
+// whose name is the Type.
+type Event struct {
+	Type     string    `json:"type"`            // "created", "updated" or "deleted"
+	ID       string    `json:"id"`              // ID of the entry that changed
+	Notebook string    `json:"notebook"`        // Notebook the entry is in; may be empty for deleted entries
+	Entry    *Entry    `json:"entry,omitempty"` // The entry after the change; not sent for deleted entries
+	Time     time.Time `json:"time"`            // RFC 3339 timestamp of the change
+}
+
+// FromEvent converts a change to an entry to its wire format. The entry is
+// only sent for changes other than "deleted".
+func FromEvent(eventType string, entry models.Entry, at time.Time) Event {
+	out := Event{
+		Type:     eventType,
+		ID:       entry.ID,
+		Notebook: entry.Notebook,
+		Time:     at,
+	}
+	if eventType != "deleted" {
+		wire := FromEntry(entry)
+		out.Entry = &wire
+	}
+	return out
+}
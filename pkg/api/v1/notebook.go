@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"journal/models"
+	"time"
+)
+
+// Notebook is a notebook as sent by the API
+type Notebook struct {
+	ID      string    `json:"id"`      // Unique identifier for the notebook
+	Name    string    `json:"name"`    // Name of the notebook, unique per user
+	Created time.Time `json:"created"` // RFC 3339 timestamp of when the notebook was created
+}
+
+// NotebookInput is the body of requests creating or renaming a notebook
+type NotebookInput struct {
+	Name string `json:"name"`
+}
+
+// FromNotebook converts a notebook to its wire format
+func FromNotebook(notebook models.Notebook) Notebook {
+	return Notebook{ID: notebook.ID, Name: notebook.Name, Created: notebook.Created}
+}
+
+// ToNotebook converts a notebook received from the API back to the model
+func ToNotebook(notebook Notebook) models.Notebook {
+	return models.Notebook{ID: notebook.ID, Name: notebook.Name, Created: notebook.Created}
+}
+
+// FromNotebooks converts a list of notebooks, returning an empty rather than
+// nil slice so it is encoded as [].
+func FromNotebooks(notebooks []models.Notebook) []Notebook {
+	out := make([]Notebook, len(notebooks))
+	for i, notebook := range notebooks {
+		out[i] = FromNotebook(notebook)
+	}
+	return out
+}
@@ -0,0 +1,9 @@
+package v1
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document describing every /api route. Keep it in
+// step with the routes registered in cmd/web and the types in this package.
+//
+//go:embed openapi.json
+var OpenAPI []byte
@@ -0,0 +1,197 @@
+package v1
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// schemaTypes are the types sent and received by the API, by the name of
+// their schema in openapi.json
+var schemaTypes = map[string]reflect.Type{
+	"BatchOperation": reflect.TypeFor[BatchOperation](),
+	"BatchRequest":   reflect.TypeFor[BatchRequest](),
+	"BatchResponse":  reflect.TypeFor[BatchResponse](),
+	"BatchResult":    reflect.TypeFor[BatchResult](),
+	"DeadLetter":     reflect.TypeFor[DeadLetter](),
+	"Entry":          reflect.TypeFor[Entry](),
+	"EntryInput":     reflect.TypeFor[EntryInput](),
+	"EntryPatch":     reflect.TypeFor[EntryPatch](),
+	"Event":          reflect.TypeFor[Event](),
+	"MoveInput":      reflect.TypeFor[MoveInput](),
+	"Notebook":       reflect.TypeFor[Notebook](),
+	"NotebookInput":  reflect.TypeFor[NotebookInput](),
+	"Problem":        reflect.TypeFor[Problem](),
+	"Webhook":        reflect.TypeFor[Webhook](),
+	"WebhookInput":   reflect.TypeFor[WebhookInput](),
+}
+
+// schema is the part of an OpenAPI schema object the tests compare
+type schema struct {
+	Ref        string            `json:"$ref"`
+	Type       string            `json:"type"`
+	Format     string            `json:"format"`
+	Items      *schema           `json:"items"`
+	Properties map[string]schema `json:"properties"`
+	Required   []string          `json:"required"`
+}
+
+// jsonField is a struct field as encoding/json sees it
+type jsonField struct {
+	goType    reflect.Type
+	omitEmpty bool
+}
+
+// jsonFields returns the fields of the struct by their JSON name
+func jsonFields(t reflect.Type) map[string]jsonField {
+	fields := map[string]jsonField{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = jsonField{goType: field.Type, omitEmpty: strings.Contains(options, "omitempty")}
+	}
+	return fields
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// checkType reports where the schema of a property doesn't match its Go type
+func checkType(t *testing.T, where string, goType reflect.Type, property schema) {
+	t.Helper()
+	if goType.Kind() == reflect.Pointer {
+		goType = goType.Elem()
+	}
+	if strings.HasPrefix(goType.Name(), "Optional[") {
+		goType = goType.Field(2).Type // Value
+	}
+	if property.Ref != "" {
+		name := strings.TrimPrefix(property.Ref, "#/components/schemas/")
+		if schemaTypes[name] != goType {
+			t.Errorf("%s refers to %s but is a %s", where, name, goType)
+		}
+		return
+	}
+
+	var want string
+	switch {
+	case goType == timeType:
+		want = "string"
+		if property.Format != "date-time" {
+			t.Errorf("%s is a time but has format %q, want date-time", where, property.Format)
+		}
+	case goType.Kind() == reflect.String:
+		want = "string"
+	case goType.Kind() == reflect.Int:
+		want = "integer"
+	case goType.Kind() == reflect.Bool:
+		want = "boolean"
+	case goType.Kind() == reflect.Slice:
+		want = "array"
+		if property.Items == nil {
+			t.Errorf("%s is an array without items", where)
+		} else {
+			checkType(t, where+"[]", goType.Elem(), *property.Items)
+		}
+	default:
+		t.Errorf("%s has Go type %s the test doesn't know", where, goType)
+		return
+	}
+	if property.Type != want {
+		t.Errorf("%s has type %q, want %q for a %s", where, property.Type, want, goType)
+	}
+}
+
+func TestOpenAPISchemasMatchTypes(t *testing.T) {
+	var document struct {
+		Components struct {
+			Schemas map[string]schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(OpenAPI, &document); err != nil {
+		t.Fatalf("parsing openapi.json: %v", err)
+	}
+	for name := range schemaTypes {
+		if _, ok := document.Components.Schemas[name]; !ok {
+			t.Errorf("%s has no schema in openapi.json", name)
+		}
+	}
+
+	for name, documented := range document.Components.Schemas {
+		goType, ok := schemaTypes[name]
+		if !ok {
+			t.Errorf("schema %s has no type in schemaTypes", name)
+			continue
+		}
+		fields := jsonFields(goType)
+		for property := range documented.Properties {
+			if _, ok := fields[property]; !ok {
+				t.Errorf("%s.%s is documented but not a field of %s", name, property, goType)
+			}
+		}
+		for property, field := range fields {
+			documentedProperty, ok := documented.Properties[property]
+			if !ok {
+				t.Errorf("%s.%s is a field of %s but not documented", name, property, goType)
+				continue
+			}
+			checkType(t, name+"."+property, field.goType, documentedProperty)
+		}
+		for _, required := range documented.Required {
+			field, ok := fields[required]
+			if !ok {
+				t.Errorf("%s.%s is required but not a field of %s", name, required, goType)
+			} else if field.omitEmpty {
+				t.Errorf("%s.%s is required but left out when empty", name, required)
+			}
+		}
+	}
+}
+
+// refs returns every $ref in the JSON value
+func refs(value any) []string {
+	var found []string
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			if ref, ok := item.(string); ok && key == "$ref" {
+				found = append(found, ref)
+			} else {
+				found = append(found, refs(item)...)
+			}
+		}
+	case []any:
+		for _, item := range value {
+			found = append(found, refs(item)...)
+		}
+	}
+	return found
+}
+
+func TestOpenAPIRefsResolve(t *testing.T) {
+	var document any
+	if err := json.Unmarshal(OpenAPI, &document); err != nil {
+		t.Fatalf("parsing openapi.json: %v", err)
+	}
+	for _, ref := range refs(document) {
+		target := document
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			object, _ := target.(map[string]any)
+			target = object[part]
+		}
+		if target == nil {
+			t.Errorf("%s doesn't resolve", ref)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package v1
+
+import "encoding/json"
+
+// Optional is a field of a patch that records whether it was present, so a
+// field left out can be told apart from one set to null or to its zero value.
+type Optional[T any] struct {
+	Set   bool // Whether the field was in the patch
+	Null  bool // Whether the field was set to null
+	Value T    // The value the field was set to, if not null
+}
+
+// Some returns a field set to the value
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{Set: true, Value: value}
+}
+
+// MarshalJSON encodes the value, or null. Fields that aren't set have to be
+// left out by the type holding them, see BatchOperation.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if o.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+// UnmarshalJSON is only called for fields present in the input
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// Ptr returns nil if the field wasn't set, a pointer to the zero value if it
+// was set to null and a pointer to the value otherwise.
+func (o Optional[T]) Ptr() *T {
+	if !o.Set {
+		return nil
+	}
+	value := o.Value
+	return &value
+}
@@ -0,0 +1,66 @@
+package v1
+
+import "errors"
+
+// Problem is an RFC 7807 problem details object, the body of every API error response.
+type Problem struct {
+	Type     string `json:"type"`               // URI identifying the kind of problem
+	Title    string `json:"title"`              // Short summary of the kind of problem
+	Status   int    `json:"status"`             // HTTP status code
+	Detail   string `json:"detail,omitempty"`   // Explanation of this occurrence of the problem
+	Instance string `json:"instance,omitempty"` // Path of the request that caused the problem
+}
+
+// Problem types of the journal's errors. Clients tell the errors apart by
+// type, since the detail is meant for people and may change. Other problems
+// have the type about:blank and are told apart by status.
+const (
+	ProblemEntryNotFound       = "urn:journal:problem:entry-not-found"
+	ProblemEntryExists         = "urn:journal:problem:entry-exists"
+	ProblemInvalidEntryID      = "urn:journal:problem:invalid-entry-id"
+	ProblemNotebookNotFound    = "urn:journal:problem:notebook-not-found"
+	ProblemNotebookExists      = "urn:journal:problem:notebook-exists"
+	ProblemNotebookNotEmpty    = "urn:journal:problem:notebook-not-empty"
+	ProblemInvalidNotebookName = "urn:journal:problem:invalid-notebook-name"
+	ProblemWebhookNotFound     = "urn:journal:problem:webhook-not-found"
+	ProblemInvalidWebhookURL   = "urn:journal:problem:invalid-webhook-url"
+	ProblemInvalidWebhookEvent = "urn:journal:problem:invalid-webhook-event"
+)
+
+// The errors reported with the problem types above. pkg/journal and
+// pkg/webhooks return these same values and the client unwraps problems to
+// them, so errors.Is works alike for a local and a remote journal. They are
+// declared here since this package depends on nothing but the models, unlike
+// the journal and its storage backends.
+var (
+	ErrEntryNotFound       = errors.New("entry not found")
+	ErrEntryExists         = errors.New("an entry with that ID already exists")
+	ErrInvalidEntryID      = errors.New("entry ID must not be blank or contain a slash")
+	ErrNotebookNotFound    = errors.New("notebook not found")
+	ErrNotebookExists      = errors.New("a notebook with that name already exists")
+	ErrNotebookNotEmpty    = errors.New("notebook still has entries; move or delete them first")
+	ErrInvalidNotebookName = errors.New("notebook name must not be blank or contain a slash")
+	ErrWebhookNotFound     = errors.New("webhook does not exist")
+	ErrInvalidWebhookURL   = errors.New("webhook URL must be an absolute http or https URL of a public host")
+	ErrInvalidWebhookEvent = errors.New(`webhook events must be "created", "updated" or "deleted"`)
+)
+
+// problemErrors are the errors by their problem type
+var problemErrors = map[string]error{
+	ProblemEntryNotFound:       ErrEntryNotFound,
+	ProblemEntryExists:         ErrEntryExists,
+	ProblemInvalidEntryID:      ErrInvalidEntryID,
+	ProblemNotebookNotFound:    ErrNotebookNotFound,
+	ProblemNotebookExists:      ErrNotebookExists,
+	ProblemNotebookNotEmpty:    ErrNotebookNotEmpty,
+	ProblemInvalidNotebookName: ErrInvalidNotebookName,
+	ProblemWebhookNotFound:     ErrWebhookNotFound,
+	ProblemInvalidWebhookURL:   ErrInvalidWebhookURL,
+	ProblemInvalidWebhookEvent: ErrInvalidWebhookEvent,
+}
+
+// ProblemError returns the error a problem type is reported for, or nil for
+// about:blank and types this version doesn't know
+func ProblemError(problemType string) error {
+	return problemErrors[problemType]
+}
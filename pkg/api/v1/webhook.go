@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"journal/models"
+	"time"
+)
+
+// Webhook is a webhook as sent by the API. The secret deliveries are signed
+// with is only sent when the webhook is created.
+type Webhook struct {
+	ID      string    `json:"id"`               // Unique identifier for the webhook
+	URL     string    `json:"url"`              // Where the events are posted to
+	Events  []string  `json:"events"`           // Event types sent, such as "created"; empty for every type
+	Secret  string    `json:"secret,omitempty"` // Key the deliveries are signed with
+	Created time.Time `json:"created"`          // RFC 3339 timestamp of when the webhook was created
+}
+
+// WebhookInput is the body of requests creating a webhook
+type WebhookInput struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"` // Empty for every event type
+}
+
+// DeadLetter is a webhook delivery that failed every attempt, as sent by the API
+type DeadLetter struct {
+	ID        string    `json:"id"`       // Unique identifier of the delivery
+	WebhookID string    `json:"webhook"`  // ID of the webhook the event was sent to
+	URL       string    `json:"url"`      // Where the event was sent
+	Event     string    `json:"event"`    // Type of the event
+	Payload   string    `json:"payload"`  // JSON body that was sent
+	Attempts  int       `json:"attempts"` // How often delivery was tried
+	Error     string    `json:"error"`    // Why the last attempt failed
+	Created   time.Time `json:"created"`  // RFC 3339 timestamp of when delivery was given up
+}
+
+// FromWebhook converts a webhook to its wire format, leaving out its secret
+func FromWebhook(webhook models.Webhook) Webhook {
+	events := webhook.Events
+	if events == nil {
+		events = []string{}
+	}
+	return Webhook{ID: webhook.ID, URL: webhook.URL, Events: events, Created: webhook.Created}
+}
+
+// ToWebhook converts a webhook received from the API back to the model
+func ToWebhook(webhook Webhook) models.Webhook {
+	return models.Webhook{ID: webhook.ID, URL: webhook.URL, Events: webhook.Events, Secret: webhook.Secret, Created: webhook.Created}
+}
+
+// FromWebhooks converts a list of webhooks, returning an empty rather than
+// nil slice so it is encoded as [].
+func FromWebhooks(webhooks []models.Webhook) []Webhook {
+	out := make([]Webhook, len(webhooks))
+	for i, webhook := range webhooks {
+		out[i] = FromWebhook(webhook)
+	}
+	return out
+}
+
+// FromDeadLetters converts a list of failed deliveries, returning an empty
+// rather than nil slice so it is encoded as [].
+func FromDeadLetters(letters []models.DeadLetter) []DeadLetter {
+	out := make([]DeadLetter, len(letters))
+	for i, letter := range letters {
+		out[i] = DeadLetter{
+			ID:        letter.ID,
+			WebhookID: letter.WebhookID,
+			URL:       letter.URL,
+			Event:     letter.Event,
+			Payload:   letter.Payload,
+			Attempts:  letter.Attempts,
+			Error:     letter.Error,
+			Created:   letter.Created,
+		}
+	}
+	return out
+}
+
+// ToDeadLetter converts a failed delivery received from the API back to the model
+func ToDeadLetter(letter DeadLetter) models.DeadLetter {
+	return models.DeadLetter{
+		ID:        letter.ID,
+		WebhookID: letter.WebhookID,
+		URL:       letter.URL,
+		Event:     letter.Event,
+		Payload:   letter.Payload,
+		Attempts:  letter.Attempts,
+		Error:     letter.Error,
+		Created:   letter.Created,
+	}
+}
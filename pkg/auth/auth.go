@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"journal/models"
+	"journal/pkg/storage"
+	"journal/pkg/utils"
+	"strings"
+	"time"
+)
+
+// SessionDuration is how long a login session stays valid
+const SessionDuration = 30 * 24 * time.Hour
+
+// MinPasswordLength is the shortest password accepted at signup
+const MinPasswordLength = 8
+
+var (
+	// ErrInvalidCredentials is returned when a username or password is wrong
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrUsernameTaken is returned when signing up with a username that already exists
+	ErrUsernameTaken = errors.New("username is already taken")
+	// ErrUsernameRequired is returned when signing up with a blank username
+	ErrUsernameRequired = errors.New("username is required")
+	// ErrPasswordTooShort is returned when signing up with a password shorter than MinPasswordLength
+	ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	// ErrInvalidSession is returned for unknown or expired session tokens
+	ErrInvalidSession = errors.New("session is invalid or has expired")
+)
+
+// Accounts manages user accounts and their login sessions.
+type Accounts struct {
+	storage storage.UserStorage
+}
+
+// NewAccounts creates a new instance of Accounts.
+func NewAccounts(store storage.UserStorage) *Accounts {
+	return &Accounts{storage: store}
+}
+
+// Signup creates a new user account. The first account created also takes
+// ownership of the entries written before accounts existed.
+func (accounts *Accounts) Signup(username, password string) (models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return models.User{}, ErrUsernameRequired
+	}
+	if len(password) < MinPasswordLength {
+		return models.User{}, ErrPasswordTooShort
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return models.User{}, err
+	}
+	count, err := accounts.storage.CountUsers()
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		ID:           utils.GenerateID(),
+		Username:     username,
+		PasswordHash: hash,
+		Created:      time.Now(),
+	}
+	err = accounts.storage.CreateUser(user)
+	if errors.Is(err, storage.ErrConflict) {
+		return models.User{}, ErrUsernameTaken
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	// Users signing up at the same time can all have counted no users, so
+	// the storage makes sure only the first of them claims the entries.
+	if count == 0 {
+		if err := accounts.storage.ClaimEntries(user.ID); err != nil {
+			return models.User{}, err
+		}
+	}
+	return user, nil
+}
+
+// Login checks the user's credentials and starts a new session. The returned
+// token is only ever held by the client; the store keeps its hash.
+func (accounts *Accounts) Login(username, password string) (string, models.Session, error) {
+	user, err := accounts.storage.GetUserByUsername(strings.TrimSpace(username))
+	if errors.Is(err, storage.ErrNotFound) {
+		// Spend the same time hashing as for a real user so response times
+		// don't reveal which usernames exist.
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return "", models.Session{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return "", models.Session{}, err
+	}
+	if !CheckPassword(user.PasswordHash, password) {
+		return "", models.Session{}, ErrInvalidCredentials
+	}
+
+	token, err := NewToken()
+	if err != nil {
+		return "", models.Session{}, err
+	}
+	session := models.Session{
+		TokenHash: HashToken(token),
+		UserID:    user.ID,
+		Created:   time.Now(),
+		Expires:   time.Now().Add(SessionDuration),
+	}
+	if err := accounts.storage.CreateSession(session); err != nil {
+		return "", models.Session{}, err
+	}
+	return token, session, nil
+}
+
+// Logout ends the session identified by the token.
+func (accounts *Accounts) Logout(token string) error {
+	return accounts.storage.DeleteSession(HashToken(token))
+}
+
+// Authenticate returns the user a session token belongs to.
+func (accounts *Accounts) Authenticate(token string) (models.User, error) {
+	session, err := accounts.storage.GetSession(HashToken(token))
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.User{}, ErrInvalidSession
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	if time.Now().After(session.Expires) {
+		accounts.storage.DeleteSession(session.TokenHash)
+		return models.User{}, ErrInvalidSession
+	}
+
+	user, err := accounts.storage.GetUser(session.UserID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.User{}, ErrInvalidSession
+	}
+	return user, err
+}
+
+// HashPassword hashes a password with bcrypt
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether the password matches the bcrypt hash
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// dummyHash is compared against when logging in as an unknown user
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("journal"), bcrypt.DefaultCost)
+
+// NewToken generates a random, URL-safe secret token
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex-encoded SHA-256 hash a token is stored under
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+	"journal/models"
+	"journal/pkg/storage"
+	"journal/pkg/utils"
+	"strings"
+	"time"
+)
+
+// TokenPrefix starts every API token so leaked tokens are easy to recognise
+const TokenPrefix = "jrnl_"
+
+var (
+	// ErrInvalidToken is returned for unknown or revoked API tokens
+	ErrInvalidToken = errors.New("API token is invalid or has been revoked")
+	// ErrTokenNameRequired is returned when creating an API token without a name
+	ErrTokenNameRequired = errors.New("token name is required")
+	// ErrInvalidScope is returned when creating an API token with an unknown scope
+	ErrInvalidScope = errors.New(`token scope must be "read" or "read-write"`)
+	// ErrTokenNotFound is returned when revoking a token the user doesn't have
+	ErrTokenNotFound = errors.New("API token does not exist")
+)
+
+// CreateToken issues a new API token for the user. The returned secret is
+// only shown once; the store keeps its hash.
+func (accounts *Accounts) CreateToken(userID, name string, scope models.TokenScope) (string, models.Token, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", models.Token{}, ErrTokenNameRequired
+	}
+	if scope != models.TokenScopeRead && scope != models.TokenScopeReadWrite {
+		return "", models.Token{}, ErrInvalidScope
+	}
+
+	secret, err := NewToken()
+	if err != nil {
+		return "", models.Token{}, err
+	}
+	secret = TokenPrefix + secret
+	token := models.Token{
+		ID:      utils.GenerateID(),
+		UserID:  userID,
+		Name:    name,
+		Hash:    HashToken(secret),
+		Scope:   scope,
+		Created: time.Now(),
+	}
+	if err := accounts.storage.CreateToken(token); err != nil {
+		return "", models.Token{}, err
+	}
+	return secret, token, nil
+}
+
+// ListTokens returns the API tokens of a user, newest first.
+func (accounts *Accounts) ListTokens(userID string) ([]models.Token, error) {
+	return accounts.storage.ListTokens(userID)
+}
+
+// RevokeToken deletes one of the user's API tokens.
+func (accounts *Accounts) RevokeToken(userID, id string) error {
+	err := accounts.storage.DeleteToken(userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrTokenNotFound
+	}
+	return err
+}
+
+// AuthenticateToken returns the user an API token belongs to along with the
+// token itself, so callers can check its scope.
+func (accounts *Accounts) AuthenticateToken(secret string) (models.User, models.Token, error) {
+	if !strings.HasPrefix(secret, TokenPrefix) {
+		return models.User{}, models.Token{}, ErrInvalidToken
+	}
+	token, err := accounts.storage.GetTokenByHash(HashToken(secret))
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.User{}, models.Token{}, ErrInvalidToken
+	}
+	if err != nil {
+		return models.User{}, models.Token{}, err
+	}
+
+	user, err := accounts.storage.GetUser(token.UserID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.User{}, models.Token{}, ErrInvalidToken
+	}
+	if err != nil {
+		return models.User{}, models.Token{}, err
+	}
+
+	token.LastUsed = time.Now()
+	if err := accounts.storage.TouchToken(token.ID, token.LastUsed); err != nil {
+		return models.User{}, models.Token{}, err
+	}
+	return user, token, nil
+}
+
+// Allows reports whether a token with the given scope may make a request
+// with the HTTP method. Read tokens are limited to safe methods.
+func Allows(scope models.TokenScope, method string) bool {
+	switch scope {
+	case models.TokenScopeReadWrite:
+		return true
+	case models.TokenScopeRead:
+		return method == "GET" || method == "HEAD" || method == "OPTIONS"
+	}
+	return false
+}
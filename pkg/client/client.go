@@ -0,0 +1,177 @@
+// Package client is a typed Go client for the journal REST API. Its methods
+// mirror journal.Journal, so code written against one can be pointed at a
+// remote server with few changes.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"journal/pkg/api/v1"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the REST API of a journal server as the owner of an API token.
+type Client struct {
+	baseURL    string // Server address without the /api prefix, e.g. http://localhost:8080
+	token      string // Personal API token sent as a Bearer token
+	notebook   string // Name of the notebook entries are scoped to; empty for every notebook
+	httpClient *http.Client
+}
+
+// New returns a client for the server at baseURL, authenticating with the API token.
+func New(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithHTTPClient returns a client sending its requests with the given
+// http.Client, for example to set a timeout.
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	scoped := *c
+	scoped.httpClient = httpClient
+	return &scoped
+}
+
+// InNotebook returns a client whose entry methods work on the named notebook
+// only. New entries are created in that notebook.
+func (c *Client) InNotebook(name string) *Client {
+	scoped := *c
+	scoped.notebook = name
+	return &scoped
+}
+
+// Notebook returns the name of the notebook the client is scoped to, if any.
+func (c *Client) Notebook() string {
+	return c.notebook
+}
+
+// Error is a problem reported by the server. It unwraps to the error of its
+// problem type, such as v1.ErrEntryNotFound, which the journal and webhooks
+// packages return as well, so errors.Is(err, journal.ErrEntryNotFound) works
+// as it does with a local journal.
+type Error struct {
+	v1.Problem
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%s: %s", e.Title, e.Detail)
+	}
+	return e.Title
+}
+
+func (e *Error) Unwrap() error {
+	return v1.ProblemError(e.Type)
+}
+
+// entriesPath returns the path of the entry collection the client is scoped to
+func (c *Client) entriesPath() string {
+	if c.notebook != "" {
+		return "/api/notebooks/" + url.PathEscape(c.notebook) + "/entries"
+	}
+	return "/api/entries"
+}
+
+// do sends a request with in encoded as the JSON body, if not nil, and
+// decodes the response body into out, if not nil.
+func (c *Client) do(method, path string, in, out any) error {
+	resp, err := c.send(method, path, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// CloseIdleConnections closes the connections to the server that aren't in use.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// Ping checks that the server can be reached and accepts the API token.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.sendContext(ctx, http.MethodGet, "/api/notebooks", nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// send sends a request with in encoded as the JSON body, if not nil. Failed
+// responses are returned as an *Error; the caller must close the body of
+// successful ones.
+func (c *Client) send(method, path string, in any) (*http.Response, error) {
+	return c.sendContext(context.Background(), method, path, in)
+}
+
+// sendContext is send with a context cancelling the request
+func (c *Client) sendContext(ctx context.Context, method, path string, in any) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case in != nil && method == http.MethodPatch:
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	case in != nil:
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// responseError reads the problem details of a failed response. Responses
+// that aren't problem+json, such as ones from a proxy, still produce an
+// Error with the status.
+func responseError(resp *http.Response) error {
+	problem := v1.Problem{}
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil || problem.Status == 0 {
+		problem = v1.Problem{Status: resp.StatusCode}
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(resp.StatusCode)
+	}
+	return &Error{Problem: problem}
+}
+
+// IsStatus reports whether err is an Error with the given HTTP status.
+func IsStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Status == status
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"journal/pkg/api/v1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// problemServer answers every request with the problem as problem+json
+func problemServer(t *testing.T, problem v1.Problem) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(problem.Status)
+		json.NewEncoder(w).Encode(problem)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestErrorUnwrapsProblemTypes(t *testing.T) {
+	tests := []struct {
+		problemType string
+		status      int
+		want        error
+	}{
+		{v1.ProblemEntryNotFound, http.StatusNotFound, v1.ErrEntryNotFound},
+		{v1.ProblemEntryExists, http.StatusConflict, v1.ErrEntryExists},
+		{v1.ProblemInvalidEntryID, http.StatusUnprocessableEntity, v1.ErrInvalidEntryID},
+		{v1.ProblemNotebookNotFound, http.StatusNotFound, v1.ErrNotebookNotFound},
+		{v1.ProblemNotebookExists, http.StatusConflict, v1.ErrNotebookExists},
+		{v1.ProblemNotebookNotEmpty, http.StatusConflict, v1.ErrNotebookNotEmpty},
+		{v1.ProblemInvalidNotebookName, http.StatusUnprocessableEntity, v1.ErrInvalidNotebookName},
+		{v1.ProblemWebhookNotFound, http.StatusNotFound, v1.ErrWebhookNotFound},
+		{v1.ProblemInvalidWebhookURL, http.StatusUnprocessableEntity, v1.ErrInvalidWebhookURL},
+		{v1.ProblemInvalidWebhookEvent, http.StatusUnprocessableEntity, v1.ErrInvalidWebhookEvent},
+	}
+	for _, test := range tests {
+		t.Run(test.problemType, func(t *testing.T) {
+			server := problemServer(t, v1.Problem{
+				Type:   test.problemType,
+				Title:  http.StatusText(test.status),
+				Status: test.status,
+				Detail: "reworded by a newer server",
+			})
+			_, err := New(server.URL, "token").GetEntry("id")
+			if !errors.Is(err, test.want) {
+				t.Errorf("got %v, want it to wrap %q", err, test.want)
+			}
+			if !IsStatus(err, test.status) {
+				t.Errorf("got %v, want status %d", err, test.status)
+			}
+		})
+	}
+}
+
+func TestErrorIgnoresDetail(t *testing.T) {
+	server := problemServer(t, v1.Problem{
+		Type:   "about:blank",
+		Title:  "Not Found",
+		Status: http.StatusNotFound,
+		Detail: v1.ErrEntryNotFound.Error(),
+	})
+	_, err := New(server.URL, "token").GetEntry("id")
+	if errors.Is(err, v1.ErrEntryNotFound) {
+		t.Errorf("got %v, want about:blank not to match a journal error", err)
+	}
+	if !IsStatus(err, http.StatusNotFound) {
+		t.Errorf("got %v, want status 404", err)
+	}
+	if want := "Not Found: " + v1.ErrEntryNotFound.Error(); err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+}
+
+func TestErrorWithoutProblemDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<html>bad gateway</html>", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL, "token").ListEntries()
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %v, want an *Error", err)
+	}
+	if apiErr.Status != http.StatusBadGateway || apiErr.Title != "Bad Gateway" {
+		t.Errorf("got status %d and title %q, want 502 Bad Gateway", apiErr.Status, apiErr.Title)
+	}
+	if apiErr.Unwrap() != nil {
+		t.Errorf("got %v, want no journal error", apiErr.Unwrap())
+	}
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"net/http"
+	"net/url"
+)
+
+// CreateEntry creates a new entry, in the client's notebook if it has one.
+func (c *Client) CreateEntry(title, content string) (models.Entry, error) {
+	var entry v1.Entry
+	err := c.do(http.MethodPost, c.entriesPath(), v1.EntryInput{Title: title, Content: content}, &entry)
+	if err != nil {
+		return models.Entry{}, err
+	}
+	return v1.ToEntry(entry), nil
+}
+
+// CreateEntryWithID creates a new entry with an ID chosen by the caller.
+// v1.ErrEntryExists, which is journal.ErrEntryExists, is returned if the ID
+// is taken.
+func (c *Client) CreateEntryWithID(id, title, content string) (models.Entry, error) {
+	var entry v1.Entry
+	err := c.do(http.MethodPost, c.entriesPath(), v1.EntryInput{ID: id, Title: title, Content: content}, &entry)
+	if err != nil {
+		return models.Entry{}, err
+	}
+	return v1.ToEntry(entry), nil
+}
+
+// ListEntries returns all the entries
+func (c *Client) ListEntries() ([]models.Entry, error) {
+	return c.SearchEntries("")
+}
+
+// SearchEntries returns the entries whose title or content contains the query, ignoring case.
+// An empty query matches every entry.
+func (c *Client) SearchEntries(query string) ([]models.Entry, error) {
+	path := c.entriesPath()
+	if query != "" {
+		path += "?q=" + url.QueryEscape(query)
+	}
+	var entries []v1.Entry
+	if err := c.do(http.MethodGet, path, nil, &entries); err != nil {
+		return []models.Entry{}, err
+	}
+	out := make([]models.Entry, len(entries))
+	for i, entry := range entries {
+		out[i] = v1.ToEntry(entry)
+	}
+	return out, nil
+}
+
+// UpdateEntry updates the title and content of an existing entry. Empty
+// values are left unchanged.
+func (c *Client) UpdateEntry(id, title, content string) (models.Entry, error) {
+	var options models.EntryUpdate
+	if title != "" {
+		options.Title = &title
+	}
+	if content != "" {
+		options.Content = &content
+	}
+	return c.PatchEntry(id, options)
+}
+
+// PatchEntry changes the fields of an existing entry that are set in the options.
+func (c *Client) PatchEntry(id string, options models.EntryUpdate) (models.Entry, error) {
+	patch := map[string]string{}
+	if options.Title != nil {
+		patch["title"] = *options.Title
+	}
+	if options.Content != nil {
+		patch["content"] = *options.Content
+	}
+	var entry v1.Entry
+	if err := c.do(http.MethodPatch, c.entriesPath()+"/"+url.PathEscape(id), patch, &entry); err != nil {
+		return models.Entry{}, err
+	}
+	return v1.ToEntry(entry), nil
+}
+
+// ReplaceEntry sets the title and content of an existing entry, including
+// to empty content.
+func (c *Client) ReplaceEntry(id, title, content string) (models.Entry, error) {
+	var entry v1.Entry
+	err := c.do(http.MethodPut, c.entriesPath()+"/"+url.PathEscape(id), v1.EntryInput{Title: title, Content: content}, &entry)
+	if err != nil {
+		return models.Entry{}, err
+	}
+	return v1.ToEntry(entry), nil
+}
+
+// GetEntry retrieves a single entry by its ID.
+func (c *Client) GetEntry(id string) (models.Entry, error) {
+	var entry v1.Entry
+	if err := c.do(http.MethodGet, c.entriesPath()+"/"+url.PathEscape(id), nil, &entry); err != nil {
+		return models.Entry{}, err
+	}
+	return v1.ToEntry(entry), nil
+}
+
+// DeleteEntry deletes an entry by its ID.
+func (c *Client) DeleteEntry(id string) error {
+	return c.do(http.MethodDelete, c.entriesPath()+"/"+url.PathEscape(id), nil, nil)
+}
+
+// ExportEntries streams every entry, in the client's notebook if it has one,
+// calling fn with each as it arrives. It stops at the first error fn returns.
+func (c *Client) ExportEntries(fn func(models.Entry) error) error {
+	resp, err := c.send(http.MethodGet, c.entriesPath()+"/export", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var entry v1.Entry
+		err := decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading export: %w", err)
+		}
+		if err := fn(v1.ToEntry(entry)); err != nil {
+			return err
+		}
+	}
+}
+
+// Batch runs several entry operations in one request, in the client's
+// notebook if it has one. Every operation succeeds or fails on its own; the
+// results are in the order of the operations.
+func (c *Client) Batch(operations []v1.BatchOperation) ([]v1.BatchResult, error) {
+	var response v1.BatchResponse
+	if err := c.do(http.MethodPost, c.entriesPath()+":batch", v1.BatchRequest{Operations: operations}, &response); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// MoveEntry files an entry in the named notebook. An empty name takes the
+// entry out of its notebook.
+func (c *Client) MoveEntry(id, notebook string) (models.Entry, error) {
+	var entry v1.Entry
+	err := c.do(http.MethodPost, "/api/entries/"+url.PathEscape(id)+"/move", v1.MoveInput{Notebook: notebook}, &entry)
+	if err != nil {
+		return models.Entry{}, err
+	}
+	return v1.ToEntry(entry), nil
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"journal/models"
+	"journal/pkg/api/v1"
+	"net/http"
+	"net/url"
+)
+
+// CreateNotebook creates a new, empty notebook.
+func (c *Client) CreateNotebook(name string) (models.Notebook, error) {
+	var notebook v1.Notebook
+	if err := c.do(http.MethodPost, "/api/notebooks", v1.NotebookInput{Name: name}, &notebook); err != nil {
+		return models.Notebook{}, err
+	}
+	return v1.ToNotebook(notebook), nil
+}
+
+// ListNotebooks returns the notebooks, sorted by name.
+func (c *Client) ListNotebooks() ([]models.Notebook, error) {
+	var notebooks []v1.Notebook
+	if err := c.do(http.MethodGet, "/api/notebooks", nil, &notebooks); err != nil {
+		return nil, err
+	}
+	out := make([]models.Notebook, len(notebooks))
+	for i, notebook := range notebooks {
+		out[i] = v1.ToNotebook(notebook)
+	}
+	return out, nil
+}
+
+// GetNotebook retrieves a notebook by its name.
+func (c *Client) GetNotebook(name string) (models.Notebook, error) {
+	var notebook v1.Notebook
+	if err := c.do(http.MethodGet, "/api/notebooks/"+url.PathEscape(name), nil, &notebook); err != nil {
+		return models.Notebook{}, err
+	}
+	return v1.ToNotebook(notebook), nil
+}
+
+// RenameNotebook renames a notebook; its entries follow it to the new name.
+func (c *Client) RenameNotebook(name, newName string) (models.Notebook, error) {
+	var notebook v1.Notebook
+	err := c.do(http.MethodPut, "/api/notebooks/"+url.PathEscape(name), v1.NotebookInput{Name: newName}, &notebook)
+	if err != nil {
+		return models.Notebook{}, err
+	}
+	return v1.ToNotebook(notebook), nil
+}
+
+// DeleteNotebook deletes a notebook. Notebooks that still hold entries are
+// not deleted and journal.ErrNotebookNotEmpty is returned.
+func (c *Client) DeleteNotebook(name string) error {
+	return c.do(http.MethodDelete, "/api/notebooks/"+url.PathEscape(name), nil, nil)
+}
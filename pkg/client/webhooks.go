@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"net/http"
+	"net/url"
+)
+
+// CreateWebhook registers a webhook sent the events of the given types, or
+// of every type if there are none. The returned webhook holds the secret
+// deliveries are signed with, which the server doesn't send again.
+func (c *Client) CreateWebhook(rawURL string, events []string) (models.Webhook, error) {
+	var webhook v1.Webhook
+	if err := c.do(http.MethodPost, "/api/webhooks", v1.WebhookInput{URL: rawURL, Events: events}, &webhook); err != nil {
+		return models.Webhook{}, err
+	}
+	return v1.ToWebhook(webhook), nil
+}
+
+// ListWebhooks returns the webhooks, oldest first, without their secrets.
+func (c *Client) ListWebhooks() ([]models.Webhook, error) {
+	var webhooks []v1.Webhook
+	if err := c.do(http.MethodGet, "/api/webhooks", nil, &webhooks); err != nil {
+		return nil, err
+	}
+	out := make([]models.Webhook, len(webhooks))
+	for i, webhook := range webhooks {
+		out[i] = v1.ToWebhook(webhook)
+	}
+	return out, nil
+}
+
+// GetWebhook retrieves a webhook by its ID, without its secret.
+func (c *Client) GetWebhook(id string) (models.Webhook, error) {
+	var webhook v1.Webhook
+	if err := c.do(http.MethodGet, "/api/webhooks/"+url.PathEscape(id), nil, &webhook); err != nil {
+		return models.Webhook{}, err
+	}
+	return v1.ToWebhook(webhook), nil
+}
+
+// DeleteWebhook removes a webhook.
+func (c *Client) DeleteWebhook(id string) error {
+	return c.do(http.MethodDelete, "/api/webhooks/"+url.PathEscape(id), nil, nil)
+}
+
+// TestWebhook has the server send a test event to a webhook. A failed
+// delivery is returned as an Error with status 502.
+func (c *Client) TestWebhook(ctx context.Context, id string) error {
+	resp, err := c.sendContext(ctx, http.MethodPost, "/api/webhooks/"+url.PathEscape(id)+"/test", nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// DeadLetters returns the deliveries to the webhooks that failed every
+// attempt, newest first.
+func (c *Client) DeadLetters() ([]models.DeadLetter, error) {
+	var letters []v1.DeadLetter
+	if err := c.do(http.MethodGet, "/api/webhooks/dead-letters", nil, &letters); err != nil {
+		return nil, err
+	}
+	out := make([]models.DeadLetter, len(letters))
+	for i, letter := range letters {
+		out[i] = v1.ToDeadLetter(letter)
+	}
+	return out, nil
+}
@@ -3,6 +3,9 @@ package journal
 import (
 	"journal/models"
 	"journal/pkg/utils"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -16,3 +19,23 @@ func NewEntry(title, content string) models.Entry {
 		Updated: time.Now(),
 	}
 }
+
+// hashtag matches a #tag starting with a letter, so Markdown headings ("# Title"),
+// issue numbers ("#12"), URL fragments and HTML entities aren't taken for tags
+var hashtag = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#(\p{L}[\p{L}\p{N}_-]*)`)
+
+// Tags returns the #hashtags in the title and content of an entry, lowercased,
+// without the # and sorted.
+func Tags(entry models.Entry) []string {
+	tags := []string{}
+	for _, text := range []string{entry.Title, entry.Content} {
+		for _, match := range hashtag.FindAllStringSubmatch(text, -1) {
+			tag := strings.ToLower(match[1])
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
@@ -0,0 +1,158 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"journal/models"
+	"journal/pkg/storage"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWatchUnsupported is returned by Watch for storage that can't report changes
+var ErrWatchUnsupported = errors.New("the storage can't be watched for changes")
+
+// EventType says what happened to an entry
+type EventType string
+
+const (
+	EntryCreated EventType = "created"
+	EntryUpdated EventType = "updated" // Also sent when an entry is moved to another notebook
+	EntryDeleted EventType = "deleted"
+)
+
+// Event describes a change to an entry made through a Journal.
+type Event struct {
+	Type  EventType
+	Entry models.Entry // The entry after the change; only ID, Owner and Notebook are set for deleted entries
+	Time  time.Time    // When the change was made
+}
+
+// subscriberBuffer is how many events a slow subscriber can fall behind
+// before further events are dropped for it.
+const subscriberBuffer = 32
+
+// Bus delivers the events of a journal to its subscribers, within the process.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]subscription
+	watched     atomic.Bool // Whether events come from watching the storage instead of the journal
+}
+
+// subscription is what a subscriber wants to hear about
+type subscription struct {
+	all      bool // Whether the subscriber hears about every owner's entries
+	owner    string
+	notebook string // Empty for every notebook
+}
+
+// NewBus creates a bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{subscribers: map[chan Event]subscription{}}
+}
+
+// Publish sends the event to every subscriber interested in it. It never
+// blocks: subscribers that fell too far behind miss the event.
+func (bus *Bus) Publish(event Event) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	for events, sub := range bus.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the events of the owner's entries in
+// the notebook, or in every notebook if it is empty. The returned function
+// ends the subscription and closes the channel.
+func (bus *Bus) Subscribe(owner, notebook string) (<-chan Event, func()) {
+	return bus.subscribe(subscription{owner: owner, notebook: notebook})
+}
+
+// SubscribeAll returns a channel receiving the events of every owner's
+// entries, for background work such as delivering webhooks. The returned
+// function ends the subscription and closes the channel.
+func (bus *Bus) SubscribeAll() (<-chan Event, func()) {
+	return bus.subscribe(subscription{all: true})
+}
+
+func (bus *Bus) subscribe(sub subscription) (<-chan Event, func()) {
+	events := make(chan Event, subscriberBuffer)
+	bus.mu.Lock()
+	bus.subscribers[events] = sub
+	bus.mu.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			bus.mu.Lock()
+			delete(bus.subscribers, events)
+			bus.mu.Unlock()
+			close(events)
+		})
+	}
+}
+
+// matches reports whether the subscriber wants the event. Deleted entries
+// may not say which notebook they were in, so deletions are always sent.
+func (sub subscription) matches(event Event) bool {
+	if sub.all {
+		return true
+	}
+	if event.Entry.Owner != sub.owner {
+		return false
+	}
+	return sub.notebook == "" || event.Type == EntryDeleted || event.Entry.Notebook == sub.notebook
+}
+
+// Subscribe returns a channel receiving the changes to the journal's
+// entries, and a function ending the subscription.
+func (journal *Journal) Subscribe() (<-chan Event, func()) {
+	return journal.events.Subscribe(journal.owner, journal.notebook)
+}
+
+// Events returns the bus the journal publishes its events on, shared with
+// every scoped copy of it.
+func (journal *Journal) Events() *Bus {
+	return journal.events
+}
+
+// publish announces a change to an entry, unless the storage is watched and
+// will report the change itself
+func (journal *Journal) publish(eventType EventType, entry models.Entry) {
+	if journal.events.watched.Load() {
+		return
+	}
+	journal.events.Publish(Event{Type: eventType, Entry: entry, Time: time.Now()})
+}
+
+// Watch publishes the changes the storage reports instead of the changes made
+// through the journal, so subscribers also hear about entries changed by other
+// processes sharing the storage. It blocks until ctx is done. The name
+// identifies the process, so it can pick up where it left off after a restart.
+//
+// The journal keeps publishing its own changes until the storage is being
+// watched, and again while the watch is retried, so a change may be reported
+// twice but is never lost.
+func (journal *Journal) Watch(ctx context.Context, name string) error {
+	watcher, ok := journal.storage.(storage.Watcher)
+	if !ok {
+		return ErrWatchUnsupported
+	}
+	defer journal.events.watched.Store(false)
+
+	err := watcher.WatchEntries(ctx, name, journal.events.watched.Store, func(change storage.Change) {
+		journal.events.Publish(Event{Type: EventType(change.Type), Entry: change.Entry, Time: change.Time})
+	})
+	if errors.Is(err, storage.ErrWatchUnsupported) {
+		return fmt.Errorf("%w: %v", ErrWatchUnsupported, err)
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package journal
+
+import (
+	"context"
+	"journal/pkg/storage"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeWatcher is a storage whose watch the test opens and fails by hand
+type fakeWatcher struct {
+	*storage.SQLiteStorage
+	watching chan func(bool)
+}
+
+func (s *fakeWatcher) WatchEntries(ctx context.Context, name string, watching func(bool), fn func(storage.Change)) error {
+	s.watching <- watching
+	<-ctx.Done()
+	return nil
+}
+
+// newWatchedJournal starts watching a journal whose storage reports no
+// changes, and returns the callback its watch was given
+func newWatchedJournal(t *testing.T) (*Journal, func(bool)) {
+	t.Helper()
+	db, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store := &fakeWatcher{SQLiteStorage: db, watching: make(chan func(bool))}
+	journal := NewJournal(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- journal.Watch(ctx, "test") }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	return journal, <-store.watching
+}
+
+// published reports whether the journal publishes an entry it creates
+func published(t *testing.T, journal *Journal) bool {
+	t.Helper()
+	events, unsubscribe := journal.Subscribe()
+	defer unsubscribe()
+	if _, err := journal.CreateEntry("Title", "Content"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-events:
+		return true
+	case <-time.After(50 * time.Millisecond):
+		return false
+	}
+}
+
+func TestWatchPublishesUntilTheStorageIsWatched(t *testing.T) {
+	journal, watching := newWatchedJournal(t)
+	if !published(t, journal) {
+		t.Error("changes made before the watch opened were lost")
+	}
+
+	watching(true)
+	if published(t, journal) {
+		t.Error("changes were published by the journal as well as the watched storage")
+	}
+
+	watching(false)
+	if !published(t, journal) {
+		t.Error("changes made while the watch is retried were lost")
+	}
+}
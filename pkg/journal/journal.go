@@ -1,15 +1,33 @@
 package journal
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"journal/models"
+	"journal/pkg/api/v1"
 	"journal/pkg/storage"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrEntryNotFound is returned when an entry with the given ID does not exist
+	ErrEntryNotFound = v1.ErrEntryNotFound
+	// ErrEntryExists is returned when creating an entry with an ID already in use
+	ErrEntryExists = v1.ErrEntryExists
+	// ErrInvalidEntryID is returned for blank entry IDs or IDs containing a slash
+	ErrInvalidEntryID = v1.ErrInvalidEntryID
 )
 
 // Journal holds a collection of entries.
 type Journal struct {
 	//entries map[string]Entry // A map to store entries with their ID as the key
-	storage storage.Storage
+	storage  storage.Storage
+	owner    string // ID of the user whose entries this journal holds
+	notebook string // Name of the notebook whose entries this journal holds; empty for every notebook
+	events   *Bus   // Shared by every scoped copy of the journal
 }
 
 // NewJournal creates a new instance of Journal.
@@ -17,17 +35,83 @@ func NewJournal(store storage.Storage) *Journal {
 	return &Journal{
 		//entries: make(map[string]Entry),
 		storage: store,
+		events:  NewBus(),
 	}
 }
 
+// ForUser returns a journal holding only the entries owned by the given user.
+func (journal *Journal) ForUser(userID string) *Journal {
+	scoped := *journal
+	scoped.owner = userID
+	return &scoped
+}
+
+// InNotebook returns a journal holding only the entries filed in the named
+// notebook. New entries are created in that notebook.
+func (journal *Journal) InNotebook(name string) *Journal {
+	scoped := *journal
+	scoped.notebook = name
+	return &scoped
+}
+
+// WithLogger returns a journal logging its storage operations to the logger,
+// such as one carrying the ID of the request they are made for.
+func (journal *Journal) WithLogger(logger *slog.Logger) *Journal {
+	scoped := *journal
+	scoped.storage = storage.Observe(journal.storage, func(operation string, duration time.Duration, err error) {
+		// Missing and conflicting records are expected answers, not failures
+		if storage.Failed(err) {
+			logger.Error("Storage operation failed", "operation", operation, "duration", duration, "err", err)
+			return
+		}
+		logger.Debug("Storage operation", "operation", operation, "duration", duration)
+	})
+	return &scoped
+}
+
+// Notebook returns the name of the notebook the journal is scoped to, if any.
+func (journal *Journal) Notebook() string {
+	return journal.notebook
+}
+
+// scope returns the storage scope of the journal's entries.
+func (journal *Journal) scope() storage.Scope {
+	return storage.Scope{Owner: journal.owner, Notebook: journal.notebook}
+}
+
 // CreateEntry creates a new journal entry and adds it to the journal.
 func (journal *Journal) CreateEntry(title, content string) (models.Entry, error) {
+	return journal.addEntry(NewEntry(title, content))
+}
+
+// CreateEntryWithID creates a new journal entry with an ID chosen by the
+// caller, such as a client that generated it before sending the entry.
+func (journal *Journal) CreateEntryWithID(id, title, content string) (models.Entry, error) {
+	if strings.TrimSpace(id) == "" || strings.Contains(id, "/") {
+		return models.Entry{}, ErrInvalidEntryID
+	}
 	entry := NewEntry(title, content)
+	entry.ID = id
+	return journal.addEntry(entry)
+}
+
+func (journal *Journal) addEntry(entry models.Entry) (models.Entry, error) {
+	if journal.notebook != "" {
+		if _, err := journal.GetNotebook(journal.notebook); err != nil {
+			return models.Entry{}, err
+		}
+	}
+	entry.Owner = journal.owner
+	entry.Notebook = journal.notebook
 	//journal.entries[entry.ID] = entry
 	if err := journal.storage.CreateEntry(entry); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			return models.Entry{}, ErrEntryExists
+		}
 		return models.Entry{}, err
 	}
 
+	journal.publish(EntryCreated, entry)
 	return entry, nil
 }
 
@@ -37,31 +121,86 @@ func (journal *Journal) ListEntries() ([]models.Entry, error) {
 	//for _, entry := range journal.entries {
 	//	entries = append(entries, entry)
 	//}
-	entries, err := journal.storage.LoadEntries()
+	entries, err := journal.storage.LoadEntries(journal.scope())
 	if err != nil {
 		return []models.Entry{}, err
 	}
 	return entries, nil
 }
 
-// UpdateEntry updates the title and content of an existing entry
+// ForEachEntry calls fn with every entry in the journal as it is read from
+// storage, so large journals can be exported without loading them in memory.
+func (journal *Journal) ForEachEntry(fn func(models.Entry) error) error {
+	return journal.storage.ForEachEntry(journal.scope(), fn)
+}
+
+// SearchEntries returns the entries whose title or content contains the query, ignoring case.
+// An empty query matches every entry.
+func (journal *Journal) SearchEntries(query string) ([]models.Entry, error) {
+	entries, err := journal.ListEntries()
+	if err != nil {
+		return []models.Entry{}, err
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return entries, nil
+	}
+	matches := []models.Entry{}
+	for _, entry := range entries {
+		if strings.Contains(strings.ToLower(entry.Title), query) || strings.Contains(strings.ToLower(entry.Content), query) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+// UpdateOptions lists the fields of an entry to change. Nil fields are left
+// as they are, so unlike with UpdateEntry a field can be set to empty.
+type UpdateOptions = models.EntryUpdate
+
+// UpdateEntry updates the title and content of an existing entry. Empty
+// values are left unchanged.
 func (journal *Journal) UpdateEntry(id, title, content string) (models.Entry, error) {
+	var options UpdateOptions
+	if title != "" {
+		options.Title = &title
+	}
+	if content != "" {
+		options.Content = &content
+	}
+	return journal.PatchEntry(id, options)
+}
+
+// PatchEntry changes the fields of an existing entry that are set in the options.
+func (journal *Journal) PatchEntry(id string, options UpdateOptions) (models.Entry, error) {
 	entry, err := journal.GetEntry(id)
 	if err != nil {
 		return models.Entry{}, err
 	}
-	entry.UpdateEntry(title, content)
+	if options.Title == nil && options.Content == nil {
+		return entry, nil
+	}
+	if options.Title != nil {
+		entry.Title = *options.Title
+	}
+	if options.Content != nil {
+		entry.Content = *options.Content
+	}
+	entry.Updated = time.Now()
 	//journal.entries[id] = entry
-	journal.storage.UpdateEntry(entry)
+	if err := journal.storage.UpdateEntry(entry); err != nil {
+		return models.Entry{}, storageError(err)
+	}
+	journal.publish(EntryUpdated, entry)
 	return entry, nil
 }
 
 // GetEntry retrieves a single entry by its ID.
 func (journal *Journal) GetEntry(id string) (models.Entry, error) {
 	//entry, exists := journal.entries[id]
-	entry, err := journal.storage.GetEntry(id)
+	entry, err := journal.storage.GetEntry(journal.scope(), id)
 	if err != nil {
-		return models.Entry{}, errors.New("entry not found")
+		return models.Entry{}, storageError(err)
 	}
 	return entry, nil
 }
@@ -71,10 +210,24 @@ func (journal *Journal) DeleteEntry(id string) error {
 	//if _, exists := journal.entries[id]; !exists {
 	//	return errors.New("entry not found")
 	//}
-	err := journal.storage.DeleteEntry(id)
+	err := journal.storage.DeleteEntry(journal.scope(), id)
 	if err != nil {
-		return errors.New("entry not found")
+		return storageError(err)
 	}
 	//delete(journal.entries, id)
+	journal.publish(EntryDeleted, models.Entry{ID: id, Owner: journal.owner, Notebook: journal.notebook})
 	return nil
 }
+
+// Ping checks that the journal's storage can be reached.
+func (journal *Journal) Ping(ctx context.Context) error {
+	return journal.storage.Ping(ctx)
+}
+
+// storageError translates a storage error into the journal's errors.
+func storageError(err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrEntryNotFound
+	}
+	return fmt.Errorf("storage: %w", err)
+}
@@ -0,0 +1,127 @@
+package journal
+
+import (
+	"errors"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"journal/pkg/storage"
+	"journal/pkg/utils"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotebookNotFound is returned when a notebook with the given name does not exist
+	ErrNotebookNotFound = v1.ErrNotebookNotFound
+	// ErrNotebookExists is returned when creating or renaming a notebook to a name already in use
+	ErrNotebookExists = v1.ErrNotebookExists
+	// ErrNotebookNotEmpty is returned when deleting a notebook that still holds entries
+	ErrNotebookNotEmpty = v1.ErrNotebookNotEmpty
+	// ErrInvalidNotebookName is returned for blank notebook names or names containing a slash
+	ErrInvalidNotebookName = v1.ErrInvalidNotebookName
+)
+
+// notebookName trims a notebook name and checks it can be used in a URL path
+func notebookName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.Contains(name, "/") {
+		return "", ErrInvalidNotebookName
+	}
+	return name, nil
+}
+
+// CreateNotebook creates a new, empty notebook.
+func (journal *Journal) CreateNotebook(name string) (models.Notebook, error) {
+	name, err := notebookName(name)
+	if err != nil {
+		return models.Notebook{}, err
+	}
+	notebook := models.Notebook{
+		ID:      utils.GenerateID(),
+		Owner:   journal.owner,
+		Name:    name,
+		Created: time.Now(),
+	}
+	if err := journal.storage.CreateNotebook(notebook); err != nil {
+		return models.Notebook{}, notebookError(err)
+	}
+	return notebook, nil
+}
+
+// ListNotebooks returns all the notebooks, sorted by name.
+func (journal *Journal) ListNotebooks() ([]models.Notebook, error) {
+	notebooks, err := journal.storage.ListNotebooks(journal.owner)
+	if err != nil {
+		return []models.Notebook{}, err
+	}
+	return notebooks, nil
+}
+
+// GetNotebook retrieves a single notebook by its name.
+func (journal *Journal) GetNotebook(name string) (models.Notebook, error) {
+	notebook, err := journal.storage.GetNotebook(journal.owner, name)
+	if err != nil {
+		return models.Notebook{}, notebookError(err)
+	}
+	return notebook, nil
+}
+
+// RenameNotebook renames a notebook, keeping its entries in it.
+func (journal *Journal) RenameNotebook(name, newName string) (models.Notebook, error) {
+	newName, err := notebookName(newName)
+	if err != nil {
+		return models.Notebook{}, err
+	}
+	if err := journal.storage.RenameNotebook(journal.owner, name, newName); err != nil {
+		return models.Notebook{}, notebookError(err)
+	}
+	return journal.GetNotebook(newName)
+}
+
+// DeleteNotebook removes an empty notebook.
+func (journal *Journal) DeleteNotebook(name string) error {
+	if _, err := journal.GetNotebook(name); err != nil {
+		return err
+	}
+	entries, err := journal.InNotebook(name).ListEntries()
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return ErrNotebookNotEmpty
+	}
+	if err := journal.storage.DeleteNotebook(journal.owner, name); err != nil {
+		return notebookError(err)
+	}
+	return nil
+}
+
+// MoveEntry files an entry in the named notebook. An empty name takes the
+// entry out of its notebook.
+func (journal *Journal) MoveEntry(id, notebook string) (models.Entry, error) {
+	if notebook != "" {
+		if _, err := journal.GetNotebook(notebook); err != nil {
+			return models.Entry{}, err
+		}
+	}
+	if err := journal.storage.MoveEntry(journal.scope(), id, notebook); err != nil {
+		return models.Entry{}, storageError(err)
+	}
+	entry, err := journal.InNotebook(notebook).GetEntry(id)
+	if err != nil {
+		return models.Entry{}, err
+	}
+	journal.publish(EntryUpdated, entry)
+	return entry, nil
+}
+
+// notebookError translates a storage error into the journal's notebook errors.
+func notebookError(err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrNotebookNotFound
+	}
+	if errors.Is(err, storage.ErrConflict) {
+		return ErrNotebookExists
+	}
+	return storageError(err)
+}
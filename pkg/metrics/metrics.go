@@ -0,0 +1,98 @@
+// Package metrics exports Prometheus metrics about the journal server: its
+// HTTP requests, its storage operations and how many entries it holds.
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"journal/pkg/storage"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// namespace prefixes the name of every metric
+const namespace = "journal"
+
+// countTimeout bounds how long counting the entries may hold up a scrape
+const countTimeout = 5 * time.Second
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests answered, by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "How long answering HTTP requests took, by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "operation_duration_seconds",
+		Help:      "How long storage operations took, by backend and operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "operation"})
+
+	storageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "errors_total",
+		Help:      "Storage operations that failed, by backend and operation. Missing and conflicting records are not counted.",
+	}, []string{"backend", "operation"})
+
+	entriesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "entries"),
+		"Entries held by the storage, across every user and notebook.",
+		[]string{"backend"}, nil,
+	)
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest records an answered HTTP request. The route is the pattern
+// the request matched, such as /api/entries/{id}, so entry IDs don't each
+// get their own series.
+func ObserveRequest(route, method string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// entryCollector reports the number of entries, counted when scraped
+type entryCollector struct {
+	counter storage.EntryCounter
+	backend string
+}
+
+// RegisterEntryCounter exports the number of entries counted by the backend,
+// labelled with its name.
+func RegisterEntryCounter(counter storage.EntryCounter, backend string) error {
+	return prometheus.Register(&entryCollector{counter: counter, backend: backend})
+}
+
+func (c *entryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- entriesDesc
+}
+
+func (c *entryCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), countTimeout)
+	defer cancel()
+	count, err := c.counter.CountEntries(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(entriesDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(count), c.backend)
+}
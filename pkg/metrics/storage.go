@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"journal/pkg/storage"
+	"time"
+)
+
+// InstrumentStorage wraps the backend so how long its operations take and
+// how many fail are recorded with the given backend name. The result is a
+// storage.Watcher when the backend is.
+func InstrumentStorage(backend storage.Storage, name string) storage.Storage {
+	return storage.Observe(backend, func(operation string, duration time.Duration, err error) {
+		storageDuration.WithLabelValues(name, operation).Observe(duration.Seconds())
+		if storage.Failed(err) {
+			storageErrors.WithLabelValues(name, operation).Inc()
+		}
+	})
+}
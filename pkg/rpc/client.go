@@ -0,0 +1,216 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"journal/models"
+	"journal/pkg/api/journalpb"
+	"journal/pkg/journal"
+)
+
+// Client calls the gRPC journal service as the owner of an API token. Its
+// methods mirror journal.Journal, with a context for every call.
+type Client struct {
+	conn     *grpc.ClientConn
+	service  journalpb.JournalServiceClient
+	notebook string // Name of the notebook entries are scoped to; empty for every notebook
+}
+
+// Dial returns a client for the server at target, such as
+// "journal.example.com:9090", authenticating with the API token. The
+// connection uses TLS unless the options say otherwise, e.g.
+// grpc.WithTransportCredentials(insecure.NewCredentials()) for local servers.
+func Dial(target, token string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})),
+		grpc.WithPerRPCCredentials(tokenCredentials(token)),
+	}, opts...)
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, service: journalpb.NewJournalServiceClient(conn)}, nil
+}
+
+// Close closes the connection of the client and its scoped copies.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// InNotebook returns a client whose entry methods work on the named notebook
+// only. New entries are created in that notebook.
+func (c *Client) InNotebook(name string) *Client {
+	scoped := *c
+	scoped.notebook = name
+	return &scoped
+}
+
+// Notebook returns the name of the notebook the client is scoped to, if any.
+func (c *Client) Notebook() string {
+	return c.notebook
+}
+
+// tokenCredentials sends the API token with every call
+type tokenCredentials string
+
+func (token tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(token)}, nil
+}
+
+// RequireTransportSecurity allows the token over plain connections, which
+// Dial only makes when asked to, for local development.
+func (token tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// journalErrors are the journal errors the server reports by their message
+var journalErrors = []error{
+	journal.ErrEntryNotFound,
+	journal.ErrEntryExists,
+	journal.ErrInvalidEntryID,
+	journal.ErrNotebookNotFound,
+	journal.ErrInvalidNotebookName,
+}
+
+// Error is a status reported by the server. It unwraps to the matching
+// journal error, so errors.Is(err, journal.ErrEntryNotFound) works as it
+// does with a local journal.
+type Error struct {
+	Status *status.Status
+}
+
+func (e *Error) Error() string {
+	return e.Status.Code().String() + ": " + e.Status.Message()
+}
+
+func (e *Error) Unwrap() error {
+	for _, err := range journalErrors {
+		if e.Status.Message() == err.Error() {
+			return err
+		}
+	}
+	return nil
+}
+
+// GRPCStatus lets status.FromError and status.Code find the status
+func (e *Error) GRPCStatus() *status.Status {
+	return e.Status
+}
+
+// clientError wraps status errors in an Error
+func clientError(err error) error {
+	if s, ok := status.FromError(err); ok && err != nil {
+		return &Error{Status: s}
+	}
+	return err
+}
+
+// CreateEntry creates a new entry
+func (c *Client) CreateEntry(ctx context.Context, title, content string) (models.Entry, error) {
+	return c.CreateEntryWithID(ctx, "", title, content)
+}
+
+// CreateEntryWithID creates a new entry with the given ID, or a generated one if it is empty
+func (c *Client) CreateEntryWithID(ctx context.Context, id, title, content string) (models.Entry, error) {
+	entry, err := c.service.CreateEntry(ctx, &journalpb.CreateEntryRequest{
+		Notebook: c.notebook,
+		Id:       id,
+		Title:    title,
+		Content:  content,
+	})
+	if err != nil {
+		return models.Entry{}, clientError(err)
+	}
+	return toEntry(entry), nil
+}
+
+// GetEntry returns a single entry
+func (c *Client) GetEntry(ctx context.Context, id string) (models.Entry, error) {
+	entry, err := c.service.GetEntry(ctx, &journalpb.GetEntryRequest{Notebook: c.notebook, Id: id})
+	if err != nil {
+		return models.Entry{}, clientError(err)
+	}
+	return toEntry(entry), nil
+}
+
+// ListEntries returns a page of the entries matching the query, oldest
+// first, and the token of the next page, which is empty on the last page.
+// A zero page size lets the server choose.
+func (c *Client) ListEntries(ctx context.Context, query string, pageSize int, pageToken string) ([]models.Entry, string, error) {
+	resp, err := c.service.ListEntries(ctx, &journalpb.ListEntriesRequest{
+		Notebook:  c.notebook,
+		Query:     query,
+		PageSize:  int32(pageSize),
+		PageToken: pageToken,
+	})
+	if err != nil {
+		return nil, "", clientError(err)
+	}
+	entries := make([]models.Entry, 0, len(resp.GetEntries()))
+	for _, entry := range resp.GetEntries() {
+		entries = append(entries, toEntry(entry))
+	}
+	return entries, resp.GetNextPageToken(), nil
+}
+
+// AllEntries returns every entry matching the query, fetching page after page.
+func (c *Client) AllEntries(ctx context.Context, query string) ([]models.Entry, error) {
+	var all []models.Entry
+	token := ""
+	for {
+		entries, next, err := c.ListEntries(ctx, query, 0, token)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, entries...)
+		if next == "" {
+			return all, nil
+		}
+		token = next
+	}
+}
+
+// UpdateEntry changes the fields set in the options and leaves the others as they are.
+func (c *Client) UpdateEntry(ctx context.Context, id string, options journal.UpdateOptions) (models.Entry, error) {
+	entry, err := c.service.UpdateEntry(ctx, &journalpb.UpdateEntryRequest{
+		Notebook: c.notebook,
+		Id:       id,
+		Title:    options.Title,
+		Content:  options.Content,
+	})
+	if err != nil {
+		return models.Entry{}, clientError(err)
+	}
+	return toEntry(entry), nil
+}
+
+// DeleteEntry deletes an entry
+func (c *Client) DeleteEntry(ctx context.Context, id string) error {
+	_, err := c.service.DeleteEntry(ctx, &journalpb.DeleteEntryRequest{Notebook: c.notebook, Id: id})
+	return clientError(err)
+}
+
+// Watch calls fn with every change to the entries until ctx is done, the
+// stream fails or fn returns an error. Cancelling ctx returns nil.
+func (c *Client) Watch(ctx context.Context, fn func(journal.Event) error) error {
+	stream, err := c.service.Watch(ctx, &journalpb.WatchRequest{Notebook: c.notebook})
+	if err != nil {
+		return clientError(err)
+	}
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil && errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return clientError(err)
+		}
+		if err := fn(toEvent(event)); err != nil {
+			return err
+		}
+	}
+}
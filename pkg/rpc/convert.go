@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"journal/models"
+	"journal/pkg/api/journalpb"
+	"journal/pkg/journal"
+	"time"
+)
+
+// fromEntry converts an entry to its protobuf message
+func fromEntry(entry models.Entry) *journalpb.Entry {
+	return &journalpb.Entry{
+		Id:       entry.ID,
+		Notebook: entry.Notebook,
+		Title:    entry.Title,
+		Content:  entry.Content,
+		Created:  timestamppb.New(entry.Created),
+		Updated:  timestamppb.New(entry.Updated),
+	}
+}
+
+// toEntry converts a protobuf message to an entry
+func toEntry(entry *journalpb.Entry) models.Entry {
+	return models.Entry{
+		ID:       entry.GetId(),
+		Notebook: entry.GetNotebook(),
+		Title:    entry.GetTitle(),
+		Content:  entry.GetContent(),
+		Created:  toTime(entry.GetCreated()),
+		Updated:  toTime(entry.GetUpdated()),
+	}
+}
+
+// toTime converts a timestamp, leaving missing ones zero
+func toTime(timestamp *timestamppb.Timestamp) time.Time {
+	if timestamp == nil {
+		return time.Time{}
+	}
+	return timestamp.AsTime()
+}
+
+var eventTypes = map[journal.EventType]journalpb.EventType{
+	journal.EntryCreated: journalpb.EventType_EVENT_TYPE_CREATED,
+	journal.EntryUpdated: journalpb.EventType_EVENT_TYPE_UPDATED,
+	journal.EntryDeleted: journalpb.EventType_EVENT_TYPE_DELETED,
+}
+
+// fromEvent converts a journal event to its protobuf message
+func fromEvent(event journal.Event) *journalpb.Event {
+	out := &journalpb.Event{
+		Type:     eventTypes[event.Type],
+		Id:       event.Entry.ID,
+		Notebook: event.Entry.Notebook,
+		Time:     timestamppb.New(event.Time),
+	}
+	if event.Type != journal.EntryDeleted {
+		out.Entry = fromEntry(event.Entry)
+	}
+	return out
+}
+
+// toEvent converts a protobuf message to a journal event. The owner of the
+// entry isn't sent, so it is left empty.
+func toEvent(event *journalpb.Event) journal.Event {
+	out := journal.Event{
+		Entry: models.Entry{ID: event.GetId(), Notebook: event.GetNotebook()},
+		Time:  toTime(event.GetTime()),
+	}
+	for eventType, pbType := range eventTypes {
+		if pbType == event.GetType() {
+			out.Type = eventType
+		}
+	}
+	if event.GetEntry() != nil {
+		out.Entry = toEntry(event.GetEntry())
+	}
+	return out
+}
@@ -0,0 +1,382 @@
+// Package rpc serves the journal over gRPC, as described by
+// journalpb/journal.proto, and provides a Go client for it.
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"journal/models"
+	"journal/pkg/api/journalpb"
+	"journal/pkg/auth"
+	"journal/pkg/journal"
+	"log/slog"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPageSize is how many entries ListEntries returns when the request doesn't say
+	defaultPageSize = 50
+	// maxPageSize caps how many entries ListEntries returns at once
+	maxPageSize = 500
+)
+
+// requestIDKey is the metadata key carrying the ID of a call, like the
+// X-Request-ID header of the web server. It is sent back in the call's header.
+const requestIDKey = "x-request-id"
+
+// validRequestID matches the request IDs taken from the metadata, so clients
+// can't write arbitrary text into the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// readMethods are the calls a read-only API token may make
+var readMethods = map[string]bool{
+	journalpb.JournalService_GetEntry_FullMethodName:    true,
+	journalpb.JournalService_ListEntries_FullMethodName: true,
+	journalpb.JournalService_Watch_FullMethodName:       true,
+}
+
+// Server implements the journal service on top of a journal, acting as the
+// owner of the API token each call is made with.
+type Server struct {
+	journalpb.UnimplementedJournalServiceServer
+	journal  *journal.Journal
+	accounts *auth.Accounts
+	stopping <-chan struct{} // Closed when the server is shutting down, ending Watch streams
+}
+
+// NewServer returns a gRPC server offering the journal service, with calls
+// authenticated by the accounts' API tokens. Serve it on its own listener.
+// The options are passed on to grpc.NewServer, such as grpc.Creds for TLS.
+func NewServer(journalInstance *journal.Journal, accounts *auth.Accounts, opts ...grpc.ServerOption) *grpc.Server {
+	return newServer(journalInstance, accounts, nil, opts)
+}
+
+// Serve serves the journal service on the listener until ctx is done. It then
+// ends the Watch streams, which would otherwise last forever, and waits for
+// the calls in progress to finish. The options are passed on to grpc.NewServer.
+func Serve(ctx context.Context, listener net.Listener, journalInstance *journal.Journal, accounts *auth.Accounts, opts ...grpc.ServerOption) error {
+	server := newServer(journalInstance, accounts, ctx.Done(), opts)
+	stopped := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+		close(stopped)
+	}()
+	if err := server.Serve(listener); err != nil {
+		return err
+	}
+	<-stopped
+	return nil
+}
+
+func newServer(journalInstance *journal.Journal, accounts *auth.Accounts, stopping <-chan struct{}, opts []grpc.ServerOption) *grpc.Server {
+	s := &Server{journal: journalInstance, accounts: accounts, stopping: stopping}
+	server := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.UnaryInterceptor(s.interceptUnary),
+		grpc.StreamInterceptor(s.interceptStream),
+	}, opts...)...)
+	journalpb.RegisterJournalServiceServer(server, s)
+	return server
+}
+
+// userKey is the context key of the user a call is made as
+type userKey struct{}
+
+// loggerKey is the context key of the logger of a call
+type loggerKey struct{}
+
+// withLogger returns a context carrying a logger with the call's method and
+// request ID, taken from the metadata or generated
+func withLogger(ctx context.Context, method string) context.Context {
+	var id string
+	if values := metadata.ValueFromIncomingContext(ctx, requestIDKey); len(values) > 0 && validRequestID.MatchString(values[0]) {
+		id = values[0]
+	} else {
+		id = newRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	logger := slog.Default().With("request_id", id, "method", method)
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// contextLogger returns the logger of the call, or the default logger
+func contextLogger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// logCall logs a finished call with its status code, as the web server logs
+// requests
+func logCall(ctx context.Context, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	contextLogger(ctx).Log(ctx, level, "Call", "code", code.String(), "duration", time.Since(start))
+}
+
+// authenticate checks the API token in the call's metadata and returns a
+// context carrying its user
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return nil, status.Error(codes.Unauthenticated, `send an API token in the "authorization" metadata as "Bearer jrnl_..."`)
+	}
+	user, token, err := s.accounts.AuthenticateToken(strings.TrimPrefix(values[0], "Bearer "))
+	if errors.Is(err, auth.ErrInvalidToken) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		contextLogger(ctx).Error("Authenticating API token failed", "err", err)
+		return nil, status.Error(codes.Internal, "authenticating the API token failed")
+	}
+	if token.Scope != models.TokenScopeReadWrite && !readMethods[method] {
+		return nil, status.Error(codes.PermissionDenied, "this API token is read-only")
+	}
+	return context.WithValue(ctx, userKey{}, user), nil
+}
+
+// interceptUnary gives the call a logger, authenticates it and logs it once answered
+func (s *Server) interceptUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	ctx = withLogger(ctx, info.FullMethod)
+	defer func(start time.Time) { logCall(ctx, start, err) }(time.Now())
+	authenticated, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	resp, err = handler(authenticated, req)
+	return resp, statusError(ctx, err)
+}
+
+// interceptStream gives the call a logger, authenticates it and logs it once it ends
+func (s *Server) interceptStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx := withLogger(stream.Context(), info.FullMethod)
+	defer func(start time.Time) { logCall(ctx, start, err) }(time.Now())
+	authenticated, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return statusError(ctx, handler(srv, &authenticatedStream{stream, authenticated}))
+}
+
+// authenticatedStream is a server stream whose context carries the user
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *authenticatedStream) Context() context.Context {
+	return stream.ctx
+}
+
+// statusError translates journal errors into gRPC status errors. Unexpected
+// errors are logged with the call's logger and reported without their details.
+func statusError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, journal.ErrEntryNotFound), errors.Is(err, journal.ErrNotebookNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, journal.ErrEntryExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, journal.ErrInvalidEntryID), errors.Is(err, journal.ErrInvalidNotebookName):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	contextLogger(ctx).Error("Call failed", "err", err)
+	return status.Error(codes.Internal, "")
+}
+
+// userJournal returns the journal of the calling user, limited to the
+// notebook if one is named
+func (s *Server) userJournal(ctx context.Context, notebook string) (*journal.Journal, error) {
+	user := ctx.Value(userKey{}).(models.User)
+	userJournal := s.journal.ForUser(user.ID).WithLogger(contextLogger(ctx))
+	if notebook == "" {
+		return userJournal, nil
+	}
+	if _, err := userJournal.GetNotebook(notebook); err != nil {
+		return nil, err
+	}
+	return userJournal.InNotebook(notebook), nil
+}
+
+// CreateEntry creates an entry, with the given ID if there is one
+func (s *Server) CreateEntry(ctx context.Context, req *journalpb.CreateEntryRequest) (*journalpb.Entry, error) {
+	if strings.TrimSpace(req.GetTitle()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "title is required")
+	}
+	if strings.TrimSpace(req.GetContent()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "content is required")
+	}
+	entries, err := s.userJournal(ctx, req.GetNotebook())
+	if err != nil {
+		return nil, err
+	}
+	var entry models.Entry
+	if req.GetId() != "" {
+		entry, err = entries.CreateEntryWithID(req.GetId(), req.GetTitle(), req.GetContent())
+	} else {
+		entry, err = entries.CreateEntry(req.GetTitle(), req.GetContent())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromEntry(entry), nil
+}
+
+// GetEntry returns a single entry
+func (s *Server) GetEntry(ctx context.Context, req *journalpb.GetEntryRequest) (*journalpb.Entry, error) {
+	entries, err := s.userJournal(ctx, req.GetNotebook())
+	if err != nil {
+		return nil, err
+	}
+	entry, err := entries.GetEntry(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return fromEntry(entry), nil
+}
+
+// ListEntries returns a page of the entries, oldest first. The page token is
+// the offset of the page, so entries created meanwhile can shift pages.
+// Storage has no paging, so every matching entry is loaded and sorted for
+// each page; listing stays as costly as listing all of a user's entries.
+func (s *Server) ListEntries(ctx context.Context, req *journalpb.ListEntriesRequest) (*journalpb.ListEntriesResponse, error) {
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+	offset, err := decodePageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.userJournal(ctx, req.GetNotebook())
+	if err != nil {
+		return nil, err
+	}
+	list, err := entries.SearchEntries(req.GetQuery())
+	if err != nil {
+		return nil, err
+	}
+	// Storage returns entries in no particular order, so sort them for stable pages
+	slices.SortFunc(list, func(a, b models.Entry) int {
+		if c := a.Created.Compare(b.Created); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	resp := &journalpb.ListEntriesResponse{Entries: []*journalpb.Entry{}}
+	end := min(offset+pageSize, len(list))
+	for _, entry := range list[min(offset, len(list)):end] {
+		resp.Entries = append(resp.Entries, fromEntry(entry))
+	}
+	if end < len(list) {
+		resp.NextPageToken = encodePageToken(end)
+	}
+	return resp, nil
+}
+
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		if offset, err := strconv.Atoi(string(decoded)); err == nil && offset >= 0 {
+			return offset, nil
+		}
+	}
+	return 0, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid page_token %q", token))
+}
+
+// UpdateEntry changes the fields that are set and leaves the others as they are
+func (s *Server) UpdateEntry(ctx context.Context, req *journalpb.UpdateEntryRequest) (*journalpb.Entry, error) {
+	if req.Title != nil && strings.TrimSpace(req.GetTitle()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "title must not be blank")
+	}
+	entries, err := s.userJournal(ctx, req.GetNotebook())
+	if err != nil {
+		return nil, err
+	}
+	entry, err := entries.PatchEntry(req.GetId(), journal.UpdateOptions{Title: req.Title, Content: req.Content})
+	if err != nil {
+		return nil, err
+	}
+	return fromEntry(entry), nil
+}
+
+// DeleteEntry deletes an entry
+func (s *Server) DeleteEntry(ctx context.Context, req *journalpb.DeleteEntryRequest) (*emptypb.Empty, error) {
+	entries, err := s.userJournal(ctx, req.GetNotebook())
+	if err != nil {
+		return nil, err
+	}
+	if err := entries.DeleteEntry(req.GetId()); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// Watch streams the changes to the entries until the call is cancelled
+func (s *Server) Watch(req *journalpb.WatchRequest, stream grpc.ServerStreamingServer[journalpb.Event]) error {
+	entries, err := s.userJournal(stream.Context(), req.GetNotebook())
+	if err != nil {
+		return err
+	}
+	events, unsubscribe := entries.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.stopping:
+			return status.Error(codes.Unavailable, "the server is shutting down")
+		case event := <-events:
+			if err := stream.Send(fromEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"journal/models"
+	"strings"
+)
+
+// encryptedPrefix starts every encrypted value, followed by the ID of the
+// data key and the base64 encoded nonce and ciphertext
+const encryptedPrefix = "enc:v1:"
+
+// ErrUnknownKey is returned when a value was encrypted with a key missing from the keyring
+var ErrUnknownKey = errors.New("value was encrypted with a key that is not in the keyring")
+
+// ErrNotEncrypted is returned when a value read isn't encrypted, which only
+// the storage returned by Migrating accepts
+var ErrNotEncrypted = errors.New("value is not encrypted")
+
+// EncryptedStorage wraps another storage and encrypts the title and content
+// of entries with AES-GCM before they reach it. Values that aren't encrypted
+// are rejected, so plaintext written straight to the database can't pass
+// for an entry. Notebook names and timestamps are not encrypted.
+type EncryptedStorage struct {
+	Storage
+	keyring   *Keyring
+	aeads     map[string]cipher.AEAD
+	plaintext bool // Whether values that aren't encrypted are read as they are
+}
+
+// NewEncryptedStorage wraps the backend so entries are encrypted with the keyring's current key
+func NewEncryptedStorage(backend Storage, keyring *Keyring) (*EncryptedStorage, error) {
+	aeads := make(map[string]cipher.AEAD, len(keyring.keys))
+	for id, key := range keyring.keys {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		aeads[id] = aead
+	}
+	if _, ok := aeads[keyring.Current]; !ok {
+		return nil, fmt.Errorf("keyring has no current key")
+	}
+	return &EncryptedStorage{Storage: backend, keyring: keyring, aeads: aeads}, nil
+}
+
+// Migrating returns a copy of the storage that reads values written before
+// encryption was set up as they are, so they can be encrypted. It can't tell
+// them from plaintext written to the database by someone else, so it is only
+// meant for re-encrypting.
+func (s *EncryptedStorage) Migrating() *EncryptedStorage {
+	migrating := *s
+	migrating.plaintext = true
+	return &migrating
+}
+
+// encrypt seals a field of an entry. The entry ID and field name are
+// authenticated so values can't be swapped between entries or fields.
+func (s *EncryptedStorage) encrypt(id, field, value string) (string, error) {
+	sealed, err := seal(s.aeads[s.keyring.Current], []byte(value), []byte(id+"/"+field))
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + s.keyring.Current + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value produced by encrypt. Plaintext values are only
+// passed through while migrating.
+func (s *EncryptedStorage) decrypt(id, field, value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		if s.plaintext {
+			return value, nil
+		}
+		return "", fmt.Errorf("entry %s: %s: %w", id, field, ErrNotEncrypted)
+	}
+	keyID, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("entry %s: malformed encrypted %s", id, field)
+	}
+	aead, ok := s.aeads[keyID]
+	if !ok {
+		return "", fmt.Errorf("entry %s: %w", id, ErrUnknownKey)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("entry %s: malformed encrypted %s: %w", id, field, err)
+	}
+	plaintext, err := open(aead, sealed, []byte(id+"/"+field))
+	if err != nil {
+		return "", fmt.Errorf("entry %s: decrypting %s failed: %w", id, field, err)
+	}
+	return string(plaintext), nil
+}
+
+func (s *EncryptedStorage) encryptEntry(entry models.Entry) (models.Entry, error) {
+	var err error
+	if entry.Title, err = s.encrypt(entry.ID, "title", entry.Title); err != nil {
+		return entry, err
+	}
+	if entry.Content, err = s.encrypt(entry.ID, "content", entry.Content); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+func (s *EncryptedStorage) decryptEntry(entry models.Entry) (models.Entry, error) {
+	var err error
+	if entry.Title, err = s.decrypt(entry.ID, "title", entry.Title); err != nil {
+		return entry, err
+	}
+	if entry.Content, err = s.decrypt(entry.ID, "content", entry.Content); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+// LoadEntries loads and decrypts the entries within the scope
+func (s *EncryptedStorage) LoadEntries(scope Scope) ([]models.Entry, error) {
+	entries, err := s.Storage.LoadEntries(scope)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if entries[i], err = s.decryptEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// ForEachEntry decrypts the entries within the scope as they are read
+func (s *EncryptedStorage) ForEachEntry(scope Scope, fn func(models.Entry) error) error {
+	return s.Storage.ForEachEntry(scope, func(entry models.Entry) error {
+		entry, err := s.decryptEntry(entry)
+		if err != nil {
+			return err
+		}
+		return fn(entry)
+	})
+}
+
+// GetEntry loads and decrypts a single entry
+func (s *EncryptedStorage) GetEntry(scope Scope, id string) (models.Entry, error) {
+	entry, err := s.Storage.GetEntry(scope, id)
+	if err != nil {
+		return entry, err
+	}
+	return s.decryptEntry(entry)
+}
+
+// CreateEntry encrypts and stores a new entry
+func (s *EncryptedStorage) CreateEntry(entry models.Entry) error {
+	entry, err := s.encryptEntry(entry)
+	if err != nil {
+		return err
+	}
+	return s.Storage.CreateEntry(entry)
+}
+
+// UpdateEntry encrypts and stores an existing entry
+func (s *EncryptedStorage) UpdateEntry(entry models.Entry) error {
+	entry, err := s.encryptEntry(entry)
+	if err != nil {
+		return err
+	}
+	return s.Storage.UpdateEntry(entry)
+}
+
+// SaveEntries encrypts and stores the entries
+func (s *EncryptedStorage) SaveEntries(entries []models.Entry) error {
+	encrypted := make([]models.Entry, len(entries))
+	for i, entry := range entries {
+		var err error
+		if encrypted[i], err = s.encryptEntry(entry); err != nil {
+			return err
+		}
+	}
+	return s.Storage.SaveEntries(encrypted)
+}
+
+// Reencrypt rewrites every entry within the scope with the current key,
+// including entries stored before encryption was set up. It returns the
+// number of entries rewritten.
+func (s *EncryptedStorage) Reencrypt(scope Scope) (int, error) {
+	entries, err := s.Migrating().LoadEntries(scope)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.SaveEntries(entries); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// ReencryptAll rewrites the entries of every owner with the current key, one
+// owner at a time. The backend must be an OwnerLister. It returns the number
+// of entries rewritten, including those of owners done before a failure.
+func (s *EncryptedStorage) ReencryptAll() (int, error) {
+	lister, ok := s.Storage.(OwnerLister)
+	if !ok {
+		return 0, errors.New("the storage can't list the owners of its entries")
+	}
+	owners, err := lister.ListOwners()
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, owner := range owners {
+		count, err := s.Reencrypt(Scope{Owner: owner})
+		total += count
+		if err != nil {
+			return total, fmt.Errorf("re-encrypting the entries of owner %q: %w", owner, err)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"errors"
+	"journal/models"
+	"testing"
+)
+
+// newTestKeyring returns a keyring with one fresh key, skipping the key file
+// and its passphrase
+func newTestKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	keyring := &Keyring{keys: map[string][]byte{}}
+	if _, err := keyring.AddKey(); err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func newTestEncrypted(t *testing.T, backend Storage, keyring *Keyring) *EncryptedStorage {
+	t.Helper()
+	encrypted, err := NewEncryptedStorage(backend, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encrypted
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	backend := newTestSQLite(t)
+	encrypted := newTestEncrypted(t, backend, newTestKeyring(t))
+	entry := models.Entry{ID: "entry", Title: "Secret title", Content: "Secret content"}
+	if err := encrypted.CreateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := backend.GetEntry(Scope{}, "entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title == entry.Title || stored.Content == entry.Content {
+		t.Errorf("got %q and %q stored, want them encrypted", stored.Title, stored.Content)
+	}
+	got, err := encrypted.GetEntry(Scope{}, "entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != entry.Title || got.Content != entry.Content {
+		t.Errorf("got %q and %q, want %q and %q", got.Title, got.Content, entry.Title, entry.Content)
+	}
+}
+
+func TestEncryptedDetectsTampering(t *testing.T) {
+	backend := newTestSQLite(t)
+	encrypted := newTestEncrypted(t, backend, newTestKeyring(t))
+	for _, entry := range []models.Entry{
+		{ID: "first", Title: "First title", Content: "First content"},
+		{ID: "second", Title: "Second title", Content: "Second content"},
+	} {
+		if err := encrypted.CreateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, _ := backend.GetEntry(Scope{}, "first")
+	second, _ := backend.GetEntry(Scope{}, "second")
+
+	tests := []struct {
+		name   string
+		entry  models.Entry
+		reason error // Expected cause, or nil for any decryption failure
+	}{
+		{"value of another entry", models.Entry{ID: "first", Title: second.Title, Content: first.Content}, nil},
+		{"value of another field", models.Entry{ID: "first", Title: first.Content, Content: first.Content}, nil},
+		{"plaintext", models.Entry{ID: "first", Title: "Forged title", Content: first.Content}, ErrNotEncrypted},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := backend.UpdateEntry(test.entry); err != nil {
+				t.Fatal(err)
+			}
+			_, err := encrypted.GetEntry(Scope{}, "first")
+			if err == nil {
+				t.Fatal("got no error, want the tampering detected")
+			}
+			if test.reason != nil && !errors.Is(err, test.reason) {
+				t.Errorf("got %v, want %v", err, test.reason)
+			}
+		})
+	}
+}
+
+func TestEncryptedUnknownKey(t *testing.T) {
+	backend := newTestSQLite(t)
+	if err := newTestEncrypted(t, backend, newTestKeyring(t)).CreateEntry(models.Entry{ID: "entry", Title: "Title", Content: "Content"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := newTestEncrypted(t, backend, newTestKeyring(t)).GetEntry(Scope{}, "entry")
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("got %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestReencryptAllMigratesPlaintext(t *testing.T) {
+	backend := newTestSQLite(t)
+	for _, entry := range []models.Entry{
+		{ID: "unowned", Title: "Title", Content: "Content"},
+		{ID: "owned", Owner: "user", Title: "Title", Content: "Content"},
+	} {
+		if err := backend.CreateEntry(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	encrypted := newTestEncrypted(t, backend, newTestKeyring(t))
+	if _, err := encrypted.GetEntry(Scope{}, "unowned"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("got %v before migrating, want %v", err, ErrNotEncrypted)
+	}
+
+	count, err := encrypted.ReencryptAll()
+	if err != nil || count != 2 {
+		t.Fatalf("got %d, %v, want both entries re-encrypted", count, err)
+	}
+	if got, err := encrypted.GetEntry(Scope{Owner: "user"}, "owned"); err != nil || got.Title != "Title" {
+		t.Errorf("got %+v, %v, want the owned entry encrypted too", got, err)
+	}
+}
+
+func TestRotateReencryptAndRetire(t *testing.T) {
+	backend := newTestSQLite(t)
+	keyring := newTestKeyring(t)
+	oldKey := keyring.Current
+	entry := models.Entry{ID: "entry", Title: "Title", Content: "Content"}
+	if err := newTestEncrypted(t, backend, keyring).CreateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	written, _ := backend.GetEntry(Scope{}, "entry")
+
+	if _, err := keyring.AddKey(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestEncrypted(t, backend, keyring).ReencryptAll(); err != nil {
+		t.Fatal(err)
+	}
+	if retired := keyring.RetireOldKeys(); len(retired) != 1 || retired[0] != oldKey {
+		t.Errorf("got %q retired, want %q", retired, oldKey)
+	}
+
+	encrypted := newTestEncrypted(t, backend, keyring)
+	if got, err := encrypted.GetEntry(Scope{}, "entry"); err != nil || got.Title != entry.Title {
+		t.Errorf("got %+v, %v, want the entry readable with the new key", got, err)
+	}
+	// A value written with the retired key, as by someone who leaked it
+	if err := backend.UpdateEntry(written); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encrypted.GetEntry(Scope{}, "entry"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("got %v, want the retired key rejected", err)
+	}
+}
+
+func TestKeyFileKeepsRetirement(t *testing.T) {
+	file, keyring, err := NewKeyFile("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey := keyring.Current
+	if _, err := keyring.AddKey(); err != nil {
+		t.Fatal(err)
+	}
+	keyring.RetireOldKeys()
+	if err := file.Wrap(keyring, "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := file.Unlock("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("got %v, want %v", err, ErrWrongPassphrase)
+	}
+	unlocked, err := file.Unlock("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := unlocked.keys[oldKey]; ok || unlocked.Current != keyring.Current {
+		t.Errorf("got keys %q with current %s, want only %s", unlocked.ids, unlocked.Current, keyring.Current)
+	}
+}
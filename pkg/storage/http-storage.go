@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"journal/pkg/client"
+	"net/http"
+	"time"
+)
+
+// errSaveUnsupported is returned by HTTPStorage.SaveEntries
+var errSaveUnsupported = errors.New("saving entries in bulk is not supported by a remote journal")
+
+// HTTPStorage stores entries on a remote journal server through its REST
+// API, so a journal.Journal can work on a shared journal as if it were local.
+// Every record belongs to the user of the API token; the owners in scopes
+// and records are ignored.
+type HTTPStorage struct {
+	client *client.Client
+}
+
+var _ Storage = (*HTTPStorage)(nil)
+
+// NewHTTPStorage returns a storage for the server at baseURL, authenticating
+// with the API token. Requests taking longer than the timeout fail; zero
+// means no timeout.
+func NewHTTPStorage(baseURL, token string, timeout time.Duration) *HTTPStorage {
+	return &HTTPStorage{
+		client: client.New(baseURL, token).WithHTTPClient(&http.Client{Timeout: timeout}),
+	}
+}
+
+// Client returns the API client the storage sends its requests with, for
+// the calls beyond entries and notebooks, such as managing webhooks.
+func (s *HTTPStorage) Client() *client.Client {
+	return s.client
+}
+
+// remoteError is an error reported by the server that also matches the
+// storage error the journal expects from a backend in the same situation.
+type remoteError struct {
+	err  error
+	kind error // ErrNotFound or ErrConflict
+}
+
+func (e *remoteError) Error() string {
+	return e.err.Error()
+}
+
+func (e *remoteError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// storageError translates the server's errors into the storage errors a
+// backend returns in the same situation. Only the problem types notFound and
+// exists are translated, so a 404 from a wrong base URL or for a missing
+// notebook isn't taken for a missing entry.
+func storageError(err error, notFound, exists string) error {
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.Type {
+	case notFound:
+		return &remoteError{err: err, kind: ErrNotFound}
+	case exists:
+		return &remoteError{err: err, kind: ErrConflict}
+	}
+	return err
+}
+
+// entryError translates the server's errors about entries
+func entryError(err error) error {
+	return storageError(err, v1.ProblemEntryNotFound, v1.ProblemEntryExists)
+}
+
+// notebookError translates the server's errors about notebooks
+func notebookError(err error) error {
+	return storageError(err, v1.ProblemNotebookNotFound, v1.ProblemNotebookExists)
+}
+
+// Ping checks that the server can be reached and accepts the API token
+func (s *HTTPStorage) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx)
+}
+
+// Close closes the idle connections to the server
+func (s *HTTPStorage) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// LoadEntries lists the entries in the scope's notebook, or every entry
+func (s *HTTPStorage) LoadEntries(scope Scope) ([]models.Entry, error) {
+	entries, err := s.client.InNotebook(scope.Notebook).ListEntries()
+	return entries, entryError(err)
+}
+
+// ForEachEntry streams the entries in the scope's notebook, or every entry,
+// from the export endpoint
+func (s *HTTPStorage) ForEachEntry(scope Scope, fn func(models.Entry) error) error {
+	return entryError(s.client.InNotebook(scope.Notebook).ExportEntries(fn))
+}
+
+// SaveEntries is not supported; the API has no way of replacing many entries at once
+func (s *HTTPStorage) SaveEntries(entries []models.Entry) error {
+	return errSaveUnsupported
+}
+
+// CreateEntry creates the entry on the server, keeping its ID. The server
+// sets its own timestamps.
+func (s *HTTPStorage) CreateEntry(entry models.Entry) error {
+	_, err := s.client.InNotebook(entry.Notebook).CreateEntryWithID(entry.ID, entry.Title, entry.Content)
+	return entryError(err)
+}
+
+// UpdateEntry saves the entry's title and content
+func (s *HTTPStorage) UpdateEntry(entry models.Entry) error {
+	_, err := s.client.ReplaceEntry(entry.ID, entry.Title, entry.Content)
+	return entryError(err)
+}
+
+// DeleteEntry deletes an entry in the scope's notebook, or in any notebook
+func (s *HTTPStorage) DeleteEntry(scope Scope, id string) error {
+	return entryError(s.client.InNotebook(scope.Notebook).DeleteEntry(id))
+}
+
+// GetEntry fetches an entry in the scope's notebook, or in any notebook
+func (s *HTTPStorage) GetEntry(scope Scope, id string) (models.Entry, error) {
+	entry, err := s.client.InNotebook(scope.Notebook).GetEntry(id)
+	return entry, entryError(err)
+}
+
+// CreateNotebook creates the notebook on the server, which assigns its own ID
+func (s *HTTPStorage) CreateNotebook(notebook models.Notebook) error {
+	_, err := s.client.CreateNotebook(notebook.Name)
+	return notebookError(err)
+}
+
+// ListNotebooks lists the notebooks of the token's user
+func (s *HTTPStorage) ListNotebooks(owner string) ([]models.Notebook, error) {
+	notebooks, err := s.client.ListNotebooks()
+	return notebooks, notebookError(err)
+}
+
+// GetNotebook fetches a notebook by name
+func (s *HTTPStorage) GetNotebook(owner, name string) (models.Notebook, error) {
+	notebook, err := s.client.GetNotebook(name)
+	return notebook, notebookError(err)
+}
+
+// RenameNotebook renames a notebook; the server moves its entries along
+func (s *HTTPStorage) RenameNotebook(owner, name, newName string) error {
+	_, err := s.client.RenameNotebook(name, newName)
+	return notebookError(err)
+}
+
+// DeleteNotebook deletes an empty notebook
+func (s *HTTPStorage) DeleteNotebook(owner, name string) error {
+	return notebookError(s.client.DeleteNotebook(name))
+}
+
+// MoveEntry files an entry in another notebook
+func (s *HTTPStorage) MoveEntry(scope Scope, id, notebook string) error {
+	if scope.Notebook != "" {
+		// The move endpoint doesn't take a notebook, so check the entry is in scope first
+		if _, err := s.GetEntry(scope, id); err != nil {
+			return err
+		}
+	}
+	_, err := s.client.MoveEntry(id, notebook)
+	return entryError(err)
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// problemServer answers every request with the problem as problem+json
+func problemServer(t *testing.T, problem v1.Problem) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(problem.Status)
+		json.NewEncoder(w).Encode(problem)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPStorageErrors(t *testing.T) {
+	getEntry := func(s *HTTPStorage) error {
+		_, err := s.GetEntry(Scope{Notebook: "work"}, "id")
+		return err
+	}
+	getNotebook := func(s *HTTPStorage) error {
+		_, err := s.GetNotebook("", "work")
+		return err
+	}
+	tests := []struct {
+		name        string
+		call        func(*HTTPStorage) error
+		problemType string
+		status      int
+		want        error
+		notWant     error // Error the problem must not be taken for
+	}{
+		{"entry not found", getEntry, v1.ProblemEntryNotFound, http.StatusNotFound, ErrNotFound, nil},
+		{"entry exists", func(s *HTTPStorage) error { return s.CreateEntry(models.Entry{ID: "id"}) }, v1.ProblemEntryExists, http.StatusConflict, ErrConflict, nil},
+		{"404 without a type", getEntry, "about:blank", http.StatusNotFound, nil, ErrNotFound},
+		{"409 without a type", getEntry, "about:blank", http.StatusConflict, nil, ErrConflict},
+		{"entry in a missing notebook", getEntry, v1.ProblemNotebookNotFound, http.StatusNotFound, v1.ErrNotebookNotFound, ErrNotFound},
+		{"notebook not found", getNotebook, v1.ProblemNotebookNotFound, http.StatusNotFound, ErrNotFound, nil},
+		{"notebook exists", func(s *HTTPStorage) error { return s.CreateNotebook(models.Notebook{Name: "work"}) }, v1.ProblemNotebookExists, http.StatusConflict, ErrConflict, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := problemServer(t, v1.Problem{Type: test.problemType, Title: http.StatusText(test.status), Status: test.status})
+			err := test.call(NewHTTPStorage(server.URL, "token", time.Second))
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if test.want != nil && !errors.Is(err, test.want) {
+				t.Errorf("got %v, want it to wrap %q", err, test.want)
+			}
+			if test.notWant != nil && errors.Is(err, test.notWant) {
+				t.Errorf("got %v, want it not to wrap %q", err, test.notWant)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"golang.org/x/crypto/argon2"
+	"os"
+	"time"
+)
+
+// Argon2id parameters used to derive the key-encryption key from a passphrase
+const (
+	argonTime    = 1
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+	keySize      = 32 // AES-256
+)
+
+// ErrWrongPassphrase is returned when a passphrase doesn't unlock the key file
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// KeyFile is the on-disk form of a keyring. The data keys that encrypt
+// entries are random and stored wrapped (encrypted) with a key derived from
+// the passphrase, so changing the passphrase never re-encrypts entries and
+// rotating keys keeps older data keys available for reading until they are
+// retired.
+type KeyFile struct {
+	Salt    []byte       // Argon2id salt for the passphrase
+	Current string       // ID of the data key new values are encrypted with
+	Keys    []WrappedKey // Every data key, oldest first
+}
+
+// WrappedKey is a data key encrypted with the passphrase-derived key
+type WrappedKey struct {
+	ID      string    // Identifier written in front of every value the key encrypted
+	Wrapped []byte    // Nonce followed by the AES-GCM sealed key
+	Created time.Time // Timestamp of when the key was generated
+}
+
+// Keyring holds the unwrapped data keys.
+type Keyring struct {
+	Current string            // ID of the key new values are encrypted with
+	ids     []string          // Key IDs, oldest first
+	keys    map[string][]byte // Keys by ID
+}
+
+// DeriveKey derives the key-encryption key from a passphrase with Argon2id
+func DeriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, keySize)
+}
+
+// NewKeyFile creates a key file with a single, fresh data key protected by the passphrase
+func NewKeyFile(passphrase string) (*KeyFile, *Keyring, error) {
+	keyring := &Keyring{keys: map[string][]byte{}}
+	if _, err := keyring.AddKey(); err != nil {
+		return nil, nil, err
+	}
+	file := &KeyFile{}
+	if err := file.Wrap(keyring, passphrase); err != nil {
+		return nil, nil, err
+	}
+	return file, keyring, nil
+}
+
+// LoadKeyFile reads a key file written by Save
+func LoadKeyFile(path string) (*KeyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file KeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Save writes the key file, readable only by the current user
+func (file *KeyFile) Save(path string) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Unlock unwraps the data keys with the passphrase
+func (file *KeyFile) Unlock(passphrase string) (*Keyring, error) {
+	return file.UnlockWithKey(DeriveKey(passphrase, file.Salt))
+}
+
+// UnlockWithKey unwraps the data keys with an already derived key-encryption key
+func (file *KeyFile) UnlockWithKey(kek []byte) (*Keyring, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	keyring := &Keyring{Current: file.Current, keys: map[string][]byte{}}
+	for _, wrapped := range file.Keys {
+		key, err := open(aead, wrapped.Wrapped, []byte(wrapped.ID))
+		if err != nil {
+			return nil, ErrWrongPassphrase
+		}
+		keyring.ids = append(keyring.ids, wrapped.ID)
+		keyring.keys[wrapped.ID] = key
+	}
+	if _, ok := keyring.keys[file.Current]; !ok {
+		return nil, errors.New("key file has no current key")
+	}
+	return keyring, nil
+}
+
+// Wrap stores every key of the keyring in the file, protected by the
+// passphrase. A new salt is generated each time.
+func (file *KeyFile) Wrap(keyring *Keyring, passphrase string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aead, err := newAEAD(DeriveKey(passphrase, salt))
+	if err != nil {
+		return err
+	}
+
+	created := map[string]time.Time{}
+	for _, wrapped := range file.Keys {
+		created[wrapped.ID] = wrapped.Created
+	}
+	var keys []WrappedKey
+	for _, id := range keyring.ids {
+		sealed, err := seal(aead, keyring.keys[id], []byte(id))
+		if err != nil {
+			return err
+		}
+		if created[id].IsZero() {
+			created[id] = time.Now()
+		}
+		keys = append(keys, WrappedKey{ID: id, Wrapped: sealed, Created: created[id]})
+	}
+
+	file.Salt = salt
+	file.Current = keyring.Current
+	file.Keys = keys
+	return nil
+}
+
+// AddKey generates a new data key and makes it the current one. Values
+// encrypted with older keys stay readable.
+func (keyring *Keyring) AddKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	keyring.Current = hex.EncodeToString(id)
+	keyring.ids = append(keyring.ids, keyring.Current)
+	keyring.keys[keyring.Current] = key
+	return keyring.Current, nil
+}
+
+// RetireOldKeys removes every key but the current one and returns their IDs.
+// Values still encrypted with them can't be read anymore, so keys are only
+// retired once every value has been re-encrypted with the current key.
+func (keyring *Keyring) RetireOldKeys() []string {
+	var retired []string
+	for _, id := range keyring.ids {
+		if id != keyring.Current {
+			retired = append(retired, id)
+			delete(keyring.keys, id)
+		}
+	}
+	keyring.ids = []string{keyring.Current}
+	return retired
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext with a random nonce, returning the nonce followed by the ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a value produced by seal
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"journal/models"
+)
+
+// createNotebookIndexes makes notebook names unique per owner
+func createNotebookIndexes(ctx context.Context, notebooks *mongo.Collection) error {
+	_, err := notebooks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "owner", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateNotebook creates a new notebook in MongoDB
+func (s *MongoDBStorage) CreateNotebook(notebook models.Notebook) error {
+	_, err := s.Notebooks.InsertOne(context.Background(), notebook)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// ListNotebooks loads the notebooks of an owner, sorted by name
+func (s *MongoDBStorage) ListNotebooks(owner string) ([]models.Notebook, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := s.Notebooks.Find(context.Background(), bson.M{"owner": owner}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var notebooks []models.Notebook
+	if err := cursor.All(context.Background(), &notebooks); err != nil {
+		return nil, err
+	}
+	return notebooks, nil
+}
+
+// GetNotebook loads a notebook by its name
+func (s *MongoDBStorage) GetNotebook(owner, name string) (models.Notebook, error) {
+	var notebook models.Notebook
+	err := s.Notebooks.FindOne(context.Background(), bson.M{"owner": owner, "name": name}).Decode(&notebook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return notebook, ErrNotFound
+	}
+	return notebook, err
+}
+
+// RenameNotebook renames a notebook and refiles its entries under the new name
+func (s *MongoDBStorage) RenameNotebook(owner, name, newName string) error {
+	result, err := s.Notebooks.UpdateOne(
+		context.Background(),
+		bson.M{"owner": owner, "name": name},
+		bson.M{"$set": bson.M{"name": newName}},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	_, err = s.DB.UpdateMany(
+		context.Background(),
+		scopeFilter(Scope{Owner: owner, Notebook: name}),
+		bson.M{"$set": bson.M{"notebook": newName}},
+	)
+	return err
+}
+
+// DeleteNotebook deletes a notebook by its name
+func (s *MongoDBStorage) DeleteNotebook(owner, name string) error {
+	result, err := s.Notebooks.DeleteOne(context.Background(), bson.M{"owner": owner, "name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MoveEntry files an entry within the scope in another notebook
+func (s *MongoDBStorage) MoveEntry(scope Scope, id, notebook string) error {
+	result, err := s.DB.UpdateOne(
+		context.Background(),
+		entryFilter(scope, id),
+		bson.M{"$set": bson.M{"notebook": notebook}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
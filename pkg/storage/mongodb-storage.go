@@ -2,30 +2,81 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"journal/models"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 )
 
 type MongoDBStorage struct {
-	DB *mongo.Collection
+	DB        *mongo.Collection // Journal entries
+	Users     *mongo.Collection
+	Sessions  *mongo.Collection
+	Tokens    *mongo.Collection // Personal API tokens
+	Notebooks *mongo.Collection
+	Webhooks  *mongo.Collection
+	// DeadLetters holds the webhook deliveries that failed every attempt
+	DeadLetters *mongo.Collection
+	// ResumeTokens holds where each change stream watcher left off
+	ResumeTokens *mongo.Collection
+	// Claims records one-time claims, such as who took over the entries
+	// created before accounts existed
+	Claims *mongo.Collection
 }
 
+// MongoDBOptions configures the connection pool of the MongoDB client. Zero
+// values keep what $MONGODB_URI says, or else the driver's defaults.
+type MongoDBOptions struct {
+	MaxPoolSize     uint64        // Most connections open to each server
+	MinPoolSize     uint64        // Connections kept open to each server even when idle
+	MaxConnIdleTime time.Duration // How long an unused connection stays open
+	Timeout         time.Duration // How long an operation may take, retries included
+}
+
+// clientOptions returns the client options for connecting to the uri
+func (opts MongoDBOptions) clientOptions(uri string) *options.ClientOptions {
+	client := options.Client().ApplyURI(uri)
+	if opts.MaxPoolSize != 0 {
+		client.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	if opts.MinPoolSize != 0 {
+		client.SetMinPoolSize(opts.MinPoolSize)
+	}
+	if opts.MaxConnIdleTime != 0 {
+		client.SetMaxConnIdleTime(opts.MaxConnIdleTime)
+	}
+	if opts.Timeout != 0 {
+		client.SetTimeout(opts.Timeout)
+	}
+	return client
+}
+
+// codeTransactionsNotSupported is the error code of a standalone server,
+// which has no transactions (IllegalOperation)
+const codeTransactionsNotSupported = 20
+
 // NewMongoDBStorage initializes the MongoDB database and returns a storage collection instance
 func NewMongoDBStorage(databaseName string, collectionName string) (*MongoDBStorage, error) {
+	return NewMongoDBStorageWithOptions(databaseName, collectionName, MongoDBOptions{})
+}
+
+// NewMongoDBStorageWithOptions is NewMongoDBStorage with the connection pool
+// configured by the options
+func NewMongoDBStorageWithOptions(databaseName string, collectionName string, opts MongoDBOptions) (*MongoDBStorage, error) {
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+		slog.Debug("No .env file found")
 	}
 	uri := os.Getenv("MONGODB_URI")
 	docs := "www.mongodb.com/docs/drivers/go/current/"
 	if uri == "" {
-		log.Fatal("Set your 'MONGODB_URI' environment variable. " +
+		return nil, errors.New("set your 'MONGODB_URI' environment variable. " +
 			"See: " + docs +
 			"usage-examples/#environment-variable")
 	}
@@ -33,47 +84,162 @@ func NewMongoDBStorage(databaseName string, collectionName string) (*MongoDBStor
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, opts.clientOptions(uri))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create mongo client: %w", err)
 	}
 
-	coll := client.Database(databaseName).Collection(collectionName)
+	database := client.Database(databaseName)
+	coll := database.Collection(collectionName)
+	if err := createEntryIndexes(ctx, coll); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	users := database.Collection("users")
+	sessions := database.Collection("sessions")
+	tokens := database.Collection("tokens")
+	if err := createUserIndexes(ctx, users, sessions, tokens); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
+	notebooks := database.Collection("notebooks")
+	if err := createNotebookIndexes(ctx, notebooks); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
+	webhooks := database.Collection("webhooks")
+	deadLetters := database.Collection("deadletters")
+	if err := createWebhookIndexes(ctx, webhooks, deadLetters); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
 
 	return &MongoDBStorage{
-		DB: coll,
+		DB:        coll,
+		Users:     users,
+		Sessions:  sessions,
+		Tokens:    tokens,
+		Notebooks: notebooks,
+		Webhooks:  webhooks,
+
+		DeadLetters:  deadLetters,
+		ResumeTokens: database.Collection("resume_tokens"),
+		Claims:       database.Collection("claims"),
 	}, nil
 }
 
+// createEntryIndexes makes entry IDs unique across owners, as the primary key
+// does in SQLite, so creating an entry with a taken ID conflicts. It fails if the collection already holds
+// duplicate IDs, which have to be renamed or removed by hand first.
+func createEntryIndexes(ctx context.Context, entries *mongo.Collection) error {
+	_, err := entries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Ping checks that the primary server can be reached
+func (s *MongoDBStorage) Ping(ctx context.Context) error {
+	return s.DB.Database().Client().Ping(ctx, readpref.Primary())
+}
+
+// CountEntries returns how many entries the collection holds, from its
+// metadata rather than by scanning it
+func (s *MongoDBStorage) CountEntries(ctx context.Context) (int64, error) {
+	return s.DB.EstimatedDocumentCount(ctx)
+}
+
+// ListOwners returns the distinct owners of the entries. The empty owner is
+// always listed, since documents written before accounts existed have no
+// owner field to be found by.
+func (s *MongoDBStorage) ListOwners() ([]string, error) {
+	values, err := s.DB.Distinct(context.Background(), "owner", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	owners := []string{""}
+	for _, value := range values {
+		if owner, ok := value.(string); ok && owner != "" {
+			owners = append(owners, owner)
+		}
+	}
+	return owners, nil
+}
+
+// Close disconnects from the MongoDB server, waiting up to 10 seconds for the
+// operations in progress to finish
+func (s *MongoDBStorage) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.DB.Database().Client().Disconnect(ctx)
+}
+
+// entryFilter matches the entry with the given ID within the scope
+func entryFilter(scope Scope, id string) bson.M {
+	filter := scopeFilter(scope)
+	filter["id"] = id
+	return filter
+}
+
+// scopeFilter matches the entries within the scope. Documents written before
+// accounts existed have no owner field, so they match the empty owner.
+func scopeFilter(scope Scope) bson.M {
+	filter := bson.M{"owner": scope.Owner}
+	if scope.Owner == "" {
+		filter["owner"] = bson.M{"$in": bson.A{"", nil}}
+	}
+	if scope.Notebook != "" {
+		filter["notebook"] = scope.Notebook
+	}
+	return filter
+}
+
 func (s *MongoDBStorage) CreateEntry(entry models.Entry) error {
 	_, err := s.DB.InsertOne(context.Background(), entry)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
 	return err
 }
 
-func (s *MongoDBStorage) LoadEntries() ([]models.Entry, error) {
-
-	cursor, err := s.DB.Find(context.Background(), bson.D{})
+func (s *MongoDBStorage) LoadEntries(scope Scope) ([]models.Entry, error) {
+	var entries []models.Entry
+	err := s.ForEachEntry(scope, func(entry models.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return entries, nil
+}
+
+// ForEachEntry reads the entries one document at a time as the cursor fetches them
+func (s *MongoDBStorage) ForEachEntry(scope Scope, fn func(models.Entry) error) error {
+	cursor, err := s.DB.Find(context.Background(), scopeFilter(scope))
+	if err != nil {
+		return err
+	}
 
 	defer cursor.Close(context.Background())
 
-	var entries []models.Entry
 	for cursor.Next(context.Background()) {
 		var entry models.Entry
 		if err := cursor.Decode(&entry); err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
 		}
-		entries = append(entries, entry)
 	}
 
-	return entries, nil
+	return cursor.Err()
 }
 
-func (s *MongoDBStorage) GetEntry(id string) (models.Entry, error) {
+func (s *MongoDBStorage) GetEntry(scope Scope, id string) (models.Entry, error) {
 	var entry models.Entry
-	err := s.DB.FindOne(context.Background(), bson.M{"id": id}).Decode(&entry)
+	err := s.DB.FindOne(context.Background(), entryFilter(scope, id)).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return entry, ErrNotFound
+	}
 	if err != nil {
 		return entry, err
 	}
@@ -81,9 +247,9 @@ func (s *MongoDBStorage) GetEntry(id string) (models.Entry, error) {
 }
 
 func (s *MongoDBStorage) UpdateEntry(entry models.Entry) error {
-	_, err := s.DB.UpdateOne(
+	result, err := s.DB.UpdateOne(
 		context.Background(),
-		bson.M{"id": entry.ID},
+		entryFilter(Scope{Owner: entry.Owner}, entry.ID),
 		bson.M{"$set": bson.M{
 			"id":      entry.ID,
 			"title":   entry.Title,
@@ -92,34 +258,74 @@ func (s *MongoDBStorage) UpdateEntry(entry models.Entry) error {
 			"updated": entry.Updated,
 		}},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-func (s *MongoDBStorage) DeleteEntry(id string) error {
-	_, err := s.DB.DeleteOne(context.Background(), bson.M{"id": id})
-	return err
+func (s *MongoDBStorage) DeleteEntry(scope Scope, id string) error {
+	result, err := s.DB.DeleteOne(context.Background(), entryFilter(scope, id))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-// SaveEntries saves the journal entries to the SQLite database (insert or update)
+// SaveEntries saves the journal entries to the MongoDB collection (insert or
+// update). An ID used by another owner's entry is a conflict, and leaves none
+// of the entries saved on a replica set. A standalone server has no
+// transactions, so there the entries before the conflict stay saved.
 func (s *MongoDBStorage) SaveEntries(entries []models.Entry) error {
-	for _, entry := range entries {
-		filter := bson.M{"id": entry.ID}
+	if len(entries) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, len(entries))
+	for i, entry := range entries {
+		filter := entryFilter(Scope{Owner: entry.Owner}, entry.ID)
 		update := bson.M{
 			"$set": bson.M{
-				"id":      entry.ID,
-				"title":   entry.Title,
-				"content": entry.Content,
-				"created": entry.Created,
-				"updated": entry.Updated,
+				"id":       entry.ID,
+				"owner":    entry.Owner,
+				"notebook": entry.Notebook,
+				"title":    entry.Title,
+				"content":  entry.Content,
+				"created":  entry.Created,
+				"updated":  entry.Updated,
 			},
 		}
-		opts := options.Update().SetUpsert(true)
-
-		_, err := s.DB.UpdateOne(context.Background(), filter, update, opts)
-		if err != nil {
-			return err
-		}
+		writes[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true)
 	}
 
-	return nil
+	// The bulk write runs in a transaction so a failure part way through
+	// leaves none of the entries written.
+	session, err := s.DB.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+	_, err = session.WithTransaction(context.Background(), func(ctx mongo.SessionContext) (any, error) {
+		return s.DB.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true))
+	})
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(codeTransactionsNotSupported) {
+		// A standalone server refuses the transaction before writing anything.
+		// Without one the entries are written in order, up to the first that
+		// fails, which is still enough for re-encrypting: the old keys are
+		// only retired once every entry was saved.
+		slog.Warn("MongoDB has no transactions without a replica set; saving entries in bulk isn't atomic")
+		_, err = s.DB.BulkWrite(context.Background(), writes, options.BulkWrite().SetOrdered(true))
+	}
+	// An entry with the ID but another owner isn't matched, so the upsert
+	// tries to insert a second one, which the unique index on id refuses
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	return err
 }
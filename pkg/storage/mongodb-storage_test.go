@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestMongo creates a database of its own on the server at $MONGODB_URI,
+// dropped after the test. Tests using it are skipped without a server.
+func newTestMongo(t *testing.T) *MongoDBStorage {
+	t.Helper()
+	if os.Getenv("MONGODB_URI") == "" {
+		t.Skip("set MONGODB_URI to run the MongoDB tests")
+	}
+	store, err := NewMongoDBStorage(fmt.Sprintf("journal_test_%d", time.Now().UnixNano()), "journal_entries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		store.DB.Database().Drop(context.Background())
+		store.Close()
+	})
+	return store
+}
+
+// replicaSet reports whether the server runs as a replica set or behind
+// mongos, and so has transactions
+func replicaSet(t *testing.T, store *MongoDBStorage) bool {
+	t.Helper()
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := store.DB.Database().RunCommand(context.Background(), bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid"
+}
+
+func TestMongoDBCreateEntryConflicts(t *testing.T) {
+	testCreateEntryConflicts(t, newTestMongo(t))
+}
+
+func TestMongoDBSaveEntriesConflicts(t *testing.T) {
+	store := newTestMongo(t)
+	testSaveEntriesConflicts(t, store, replicaSet(t, store))
+}
+
+func TestMongoDBSaveEntriesUpserts(t *testing.T) {
+	testSaveEntriesUpserts(t, newTestMongo(t))
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"journal/models"
+	"time"
+)
+
+// createUserIndexes makes usernames unique and lets MongoDB expire old sessions
+func createUserIndexes(ctx context.Context, users, sessions, tokens *mongo.Collection) error {
+	_, err := users.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = sessions.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tokenhash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tokens.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userid", Value: 1}}},
+	})
+	return err
+}
+
+// CreateUser creates a new user account in MongoDB
+func (s *MongoDBStorage) CreateUser(user models.User) error {
+	_, err := s.Users.InsertOne(context.Background(), user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// GetUser loads a user account by its ID
+func (s *MongoDBStorage) GetUser(id string) (models.User, error) {
+	return s.findUser(bson.M{"id": id})
+}
+
+// GetUserByUsername loads a user account by its username
+func (s *MongoDBStorage) GetUserByUsername(username string) (models.User, error) {
+	return s.findUser(bson.M{"username": username})
+}
+
+func (s *MongoDBStorage) findUser(filter bson.M) (models.User, error) {
+	var user models.User
+	err := s.Users.FindOne(context.Background(), filter).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+// CountUsers returns the number of user accounts
+func (s *MongoDBStorage) CountUsers() (int, error) {
+	count, err := s.Users.CountDocuments(context.Background(), bson.D{})
+	return int(count), err
+}
+
+// unownedEntriesClaim is the ID of the claim recording who took over the
+// entries created before accounts existed
+const unownedEntriesClaim = "unowned-entries"
+
+// ClaimEntries assigns every entry without an owner to the given user, once.
+// The claim is recorded first and its ID is unique, so of the users signing
+// up at the same time only one gets to claim the entries.
+func (s *MongoDBStorage) ClaimEntries(owner string) error {
+	_, err := s.Claims.InsertOne(context.Background(), bson.M{"_id": unownedEntriesClaim, "owner": owner})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.UpdateMany(
+		context.Background(),
+		scopeFilter(Scope{}),
+		bson.M{"$set": bson.M{"owner": owner}},
+	)
+	return err
+}
+
+// CreateSession stores a new login session
+func (s *MongoDBStorage) CreateSession(session models.Session) error {
+	_, err := s.Sessions.InsertOne(context.Background(), session)
+	return err
+}
+
+// GetSession loads a login session by the hash of its token
+func (s *MongoDBStorage) GetSession(tokenHash string) (models.Session, error) {
+	var session models.Session
+	err := s.Sessions.FindOne(context.Background(), bson.M{"tokenhash": tokenHash}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return session, ErrNotFound
+	}
+	return session, err
+}
+
+// DeleteSession removes a login session
+func (s *MongoDBStorage) DeleteSession(tokenHash string) error {
+	_, err := s.Sessions.DeleteOne(context.Background(), bson.M{"tokenhash": tokenHash})
+	return err
+}
+
+// CreateToken stores a new API token
+func (s *MongoDBStorage) CreateToken(token models.Token) error {
+	_, err := s.Tokens.InsertOne(context.Background(), token)
+	return err
+}
+
+// ListTokens loads the API tokens of a user, newest first
+func (s *MongoDBStorage) ListTokens(userID string) ([]models.Token, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created", Value: -1}})
+	cursor, err := s.Tokens.Find(context.Background(), bson.M{"userid": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []models.Token
+	if err := cursor.All(context.Background(), &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// GetTokenByHash loads an API token by the hash of its secret
+func (s *MongoDBStorage) GetTokenByHash(hash string) (models.Token, error) {
+	var token models.Token
+	err := s.Tokens.FindOne(context.Background(), bson.M{"hash": hash}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return token, ErrNotFound
+	}
+	return token, err
+}
+
+// TouchToken records when an API token was last used
+func (s *MongoDBStorage) TouchToken(id string, used time.Time) error {
+	_, err := s.Tokens.UpdateOne(context.Background(), bson.M{"id": id}, bson.M{"$set": bson.M{"lastused": used}})
+	return err
+}
+
+// DeleteToken revokes one of a user's API tokens
+func (s *MongoDBStorage) DeleteToken(userID, id string) error {
+	result, err := s.Tokens.DeleteOne(context.Background(), bson.M{"id": id, "userid": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"journal/models"
+	"log/slog"
+	"time"
+)
+
+// watchRetryDelay is how long WatchEntries waits before reopening a change
+// stream that failed
+const watchRetryDelay = 5 * time.Second
+
+// Error codes of change streams that can't be resumed from the saved token
+const (
+	codeChangeStreamHistoryLost = 286
+	codeChangeStreamFatalError  = 280
+)
+
+// codeChangeStreamNotSupported is the error code of a standalone server,
+// which has no change streams
+const codeChangeStreamNotSupported = 40573
+
+// resumeToken is a watcher's position in the change stream, saved in the
+// resume_tokens collection
+type resumeToken struct {
+	Name    string    `bson:"name"`
+	Token   bson.Raw  `bson:"token"`
+	Updated time.Time `bson:"updated"`
+}
+
+// entryChange is the part of a change stream event WatchEntries reads
+type entryChange struct {
+	OperationType            string        `bson:"operationType"`
+	FullDocument             *models.Entry `bson:"fullDocument"`
+	FullDocumentBeforeChange *models.Entry `bson:"fullDocumentBeforeChange"`
+	WallTime                 time.Time     `bson:"wallTime"`
+}
+
+// WatchEntries follows the entries collection with a change stream, so it
+// sees writes made by every server and by hand. Change streams need a replica
+// set, which Atlas always is. The resume token is saved after every change.
+//
+// Deleted documents can only be reported when MongoDB keeps pre-images for
+// the collection (6.0 or later). WatchEntries turns them on, and returns
+// ErrWatchUnsupported when it can't, since deletions would go unreported.
+//
+// watching is called with true once a change stream is open, and with false
+// when it fails, until the next one opens.
+func (s *MongoDBStorage) WatchEntries(ctx context.Context, name string, watching func(bool), fn func(Change)) error {
+	if err := s.enablePreImages(ctx); err != nil {
+		return fmt.Errorf("%w: deleted entries can't be reported: %v", ErrWatchUnsupported, err)
+	}
+	for {
+		err := s.watchEntries(ctx, name, watching, fn)
+		watching(false)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var serverErr mongo.ServerError
+		if errors.As(err, &serverErr) && serverErr.HasErrorCode(codeChangeStreamNotSupported) {
+			return fmt.Errorf("%w: change streams need MongoDB to run as a replica set", ErrWatchUnsupported)
+		}
+		if errors.As(err, &serverErr) &&
+			(serverErr.HasErrorCode(codeChangeStreamHistoryLost) || serverErr.HasErrorCode(codeChangeStreamFatalError)) {
+			slog.Warn("Change stream can't resume, starting from now", "name", name, "err", err)
+			if _, err := s.ResumeTokens.DeleteOne(ctx, bson.M{"name": name}); err != nil {
+				return err
+			}
+			continue
+		}
+		slog.Error("Change stream failed, retrying", "name", name, "delay", watchRetryDelay, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
+
+// watchEntries reads a change stream until it fails or ctx is done
+func (s *MongoDBStorage) watchEntries(ctx context.Context, name string, watching func(bool), fn func(Change)) error {
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	var saved resumeToken
+	err := s.ResumeTokens.FindOne(ctx, bson.M{"name": name}).Decode(&saved)
+	switch {
+	case err == nil:
+		// StartAfter rather than ResumeAfter also resumes after an invalidate event
+		opts.SetStartAfter(saved.Token)
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		return err
+	}
+
+	stream, err := s.DB.Watch(ctx, mongo.Pipeline{}, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+	watching(true)
+
+	for stream.Next(ctx) {
+		var event entryChange
+		if err := stream.Decode(&event); err != nil {
+			return err
+		}
+		if change, ok := event.change(); ok {
+			fn(change)
+		}
+		if event.OperationType == "invalidate" {
+			// The collection was dropped or renamed; saving the token lets the
+			// next stream start after it
+			if err := s.saveResumeToken(ctx, name, stream.ResumeToken()); err != nil {
+				return err
+			}
+			return errors.New("change stream invalidated")
+		}
+		if err := s.saveResumeToken(ctx, name, stream.ResumeToken()); err != nil {
+			return err
+		}
+	}
+	return stream.Err()
+}
+
+// change converts a change stream event into a Change, if it is about an entry
+func (event entryChange) change() (Change, bool) {
+	switch event.OperationType {
+	case "insert":
+		if event.FullDocument != nil {
+			return Change{Type: ChangeCreated, Entry: *event.FullDocument, Time: event.time()}, true
+		}
+	case "update", "replace":
+		// The document is looked up when the event is read, so it is missing
+		// if the entry was deleted since
+		if event.FullDocument != nil {
+			return Change{Type: ChangeUpdated, Entry: *event.FullDocument, Time: event.time()}, true
+		}
+	case "delete":
+		if before := event.FullDocumentBeforeChange; before != nil {
+			entry := models.Entry{ID: before.ID, Owner: before.Owner, Notebook: before.Notebook}
+			return Change{Type: ChangeDeleted, Entry: entry, Time: event.time()}, true
+		}
+	}
+	return Change{}, false
+}
+
+// time returns when the change was made; wallTime is only sent by MongoDB 6.0 and later
+func (event entryChange) time() time.Time {
+	if event.WallTime.IsZero() {
+		return time.Now()
+	}
+	return event.WallTime
+}
+
+func (s *MongoDBStorage) saveResumeToken(ctx context.Context, name string, token bson.Raw) error {
+	_, err := s.ResumeTokens.UpdateOne(ctx,
+		bson.M{"name": name},
+		bson.M{"$set": resumeToken{Name: name, Token: token, Updated: time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// enablePreImages asks MongoDB to keep the documents deleted from the entries
+// collection, so their deletion can be attributed to an owner. Users without
+// the privilege to change collections can still watch if an administrator
+// turned pre-images on; older servers can't keep them at all.
+func (s *MongoDBStorage) enablePreImages(ctx context.Context) error {
+	command := bson.D{
+		{Key: "collMod", Value: s.DB.Name()},
+		{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": true}},
+	}
+	err := s.DB.Database().RunCommand(ctx, command).Err()
+	if err == nil {
+		return nil
+	}
+	var collection struct {
+		Options struct {
+			PreImages struct {
+				Enabled bool `bson:"enabled"`
+			} `bson:"changeStreamPreAndPostImages"`
+		} `bson:"options"`
+	}
+	cursor, listErr := s.DB.Database().ListCollections(ctx, bson.M{"name": s.DB.Name()})
+	if listErr != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	if cursor.Next(ctx) && cursor.Decode(&collection) == nil && collection.Options.PreImages.Enabled {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"journal/models"
+	"testing"
+	"time"
+)
+
+func TestEntryChangeOfDeletion(t *testing.T) {
+	before := &models.Entry{ID: "id", Owner: "owner", Notebook: "notebook", Title: "Title", Content: "Content"}
+	change, ok := entryChange{OperationType: "delete", FullDocumentBeforeChange: before}.change()
+	if !ok {
+		t.Fatal("a deletion with its pre-image wasn't reported")
+	}
+	want := models.Entry{ID: "id", Owner: "owner", Notebook: "notebook"}
+	if change.Type != ChangeDeleted || change.Entry != want {
+		t.Errorf("got %s %+v, want deleted %+v", change.Type, change.Entry, want)
+	}
+
+	// Without the pre-image the owner is unknown, so no one may hear of it
+	if _, ok := (entryChange{OperationType: "delete"}).change(); ok {
+		t.Error("a deletion without its pre-image was reported")
+	}
+}
+
+// startWatch watches the storage until the test ends or stop is called, and
+// returns once the change stream is open
+func startWatch(t *testing.T, store *MongoDBStorage, name string) (<-chan Change, func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan Change, 16)
+	opened := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- store.WatchEntries(ctx, name, func(watching bool) {
+			if watching {
+				opened <- struct{}{}
+			}
+		}, func(change Change) { changes <- change })
+	}()
+
+	select {
+	case <-opened:
+	case err := <-done:
+		t.Fatalf("watching failed: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the change stream didn't open")
+	}
+	var stopped bool
+	stop := func() {
+		if !stopped {
+			stopped = true
+			cancel()
+			if err := <-done; err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	t.Cleanup(stop)
+	return changes, stop
+}
+
+// nextChange waits for the watch to report a change
+func nextChange(t *testing.T, changes <-chan Change) Change {
+	t.Helper()
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(10 * time.Second):
+		t.Fatal("no change was reported")
+		return Change{}
+	}
+}
+
+func TestMongoDBWatchReportsDeletionsFromPreImages(t *testing.T) {
+	store := newTestMongo(t)
+	if !replicaSet(t, store) {
+		t.Skip("change streams need MongoDB to run as a replica set")
+	}
+	changes, _ := startWatch(t, store, "test")
+
+	entry := models.Entry{ID: "deleted", Owner: "owner", Notebook: "notebook", Title: "Title", Content: "Content"}
+	if err := store.CreateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteEntry(Scope{Owner: "owner"}, "deleted"); err != nil {
+		t.Fatal(err)
+	}
+
+	if change := nextChange(t, changes); change.Type != ChangeCreated {
+		t.Fatalf("got %s, want created", change.Type)
+	}
+	change := nextChange(t, changes)
+	want := models.Entry{ID: "deleted", Owner: "owner", Notebook: "notebook"}
+	if change.Type != ChangeDeleted || change.Entry != want {
+		t.Errorf("got %s %+v, want deleted %+v", change.Type, change.Entry, want)
+	}
+}
+
+func TestMongoDBWatchResumesFromItsToken(t *testing.T) {
+	store := newTestMongo(t)
+	if !replicaSet(t, store) {
+		t.Skip("change streams need MongoDB to run as a replica set")
+	}
+	changes, stop := startWatch(t, store, "test")
+	if err := store.CreateEntry(models.Entry{ID: "seen", Title: "Title", Content: "Content"}); err != nil {
+		t.Fatal(err)
+	}
+	nextChange(t, changes)
+	stop()
+
+	// Made while no one watched, so only the saved token can bring it back
+	if err := store.CreateEntry(models.Entry{ID: "missed", Title: "Title", Content: "Content"}); err != nil {
+		t.Fatal(err)
+	}
+	changes, _ = startWatch(t, store, "test")
+	if change := nextChange(t, changes); change.Entry.ID != "missed" {
+		t.Errorf("got %s %s first after resuming, want the entry created while stopped", change.Type, change.Entry.ID)
+	}
+}
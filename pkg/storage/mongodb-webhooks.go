@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"journal/models"
+)
+
+// createWebhookIndexes makes webhook IDs unique and speeds up looking them up by owner
+func createWebhookIndexes(ctx context.Context, webhooks, deadLetters *mongo.Collection) error {
+	_, err := webhooks.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "owner", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = deadLetters.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "owner", Value: 1}, {Key: "created", Value: -1}},
+	})
+	return err
+}
+
+// CreateWebhook stores a new webhook in MongoDB
+func (s *MongoDBStorage) CreateWebhook(webhook models.Webhook) error {
+	_, err := s.Webhooks.InsertOne(context.Background(), webhook)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// ListWebhooks loads the webhooks of a user, oldest first
+func (s *MongoDBStorage) ListWebhooks(owner string) ([]models.Webhook, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created", Value: 1}})
+	cursor, err := s.Webhooks.Find(context.Background(), bson.M{"owner": owner}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var webhooks []models.Webhook
+	if err := cursor.All(context.Background(), &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhook loads one of a user's webhooks
+func (s *MongoDBStorage) GetWebhook(owner, id string) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := s.Webhooks.FindOne(context.Background(), bson.M{"id": id, "owner": owner}).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return webhook, ErrNotFound
+	}
+	return webhook, err
+}
+
+// DeleteWebhook removes one of a user's webhooks
+func (s *MongoDBStorage) DeleteWebhook(owner, id string) error {
+	result, err := s.Webhooks.DeleteOne(context.Background(), bson.M{"id": id, "owner": owner})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddDeadLetter records a webhook delivery that failed every attempt
+func (s *MongoDBStorage) AddDeadLetter(letter models.DeadLetter) error {
+	_, err := s.DeadLetters.InsertOne(context.Background(), letter)
+	return err
+}
+
+// ListDeadLetters loads the failed deliveries of a user's webhooks, newest first
+func (s *MongoDBStorage) ListDeadLetters(owner string) ([]models.DeadLetter, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created", Value: -1}})
+	cursor, err := s.DeadLetters.Find(context.Background(), bson.M{"owner": owner}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var letters []models.DeadLetter
+	if err := cursor.All(context.Background(), &letters); err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"journal/models"
+	"time"
+)
+
+// Observer is called after every entry and notebook operation of an
+// ObservedStorage with the operation's name, such as "get_entry", how long
+// it took and the error it returned.
+type Observer func(operation string, duration time.Duration, err error)
+
+// ObservedStorage wraps another storage and reports each of its entry and
+// notebook operations to an observer, such as one recording metrics or
+// writing logs. Accounts and webhooks are not observed.
+type ObservedStorage struct {
+	backend  Storage
+	observer Observer
+}
+
+// observedWatcher is an ObservedStorage whose backend can also be watched for changes
+type observedWatcher struct {
+	*ObservedStorage
+	watcher Watcher
+}
+
+// WatchEntries watches the backend; it isn't observed, since it lasts as long as the server
+func (s *observedWatcher) WatchEntries(ctx context.Context, name string, watching func(bool), fn func(Change)) error {
+	return s.watcher.WatchEntries(ctx, name, watching, fn)
+}
+
+// Observe wraps the backend so its operations are reported to the observer.
+// The result is a Watcher when the backend is.
+func Observe(backend Storage, observer Observer) Storage {
+	s := &ObservedStorage{backend: backend, observer: observer}
+	if watcher, ok := backend.(Watcher); ok {
+		return &observedWatcher{ObservedStorage: s, watcher: watcher}
+	}
+	return s
+}
+
+// Failed tells whether an operation's error is a failure rather than an
+// expected answer: missing and conflicting records are not failures.
+func Failed(err error) bool {
+	return err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrConflict)
+}
+
+// observe reports an operation that started at start. It is deferred with
+// the address of the operation's error, so it sees the error returned.
+func (s *ObservedStorage) observe(operation string, start time.Time, errp *error) {
+	s.observer(operation, time.Since(start), *errp)
+}
+
+func (s *ObservedStorage) LoadEntries(scope Scope) (entries []models.Entry, err error) {
+	defer s.observe("load_entries", time.Now(), &err)
+	return s.backend.LoadEntries(scope)
+}
+
+func (s *ObservedStorage) SaveEntries(entries []models.Entry) (err error) {
+	defer s.observe("save_entries", time.Now(), &err)
+	return s.backend.SaveEntries(entries)
+}
+
+func (s *ObservedStorage) CreateEntry(entry models.Entry) (err error) {
+	defer s.observe("create_entry", time.Now(), &err)
+	return s.backend.CreateEntry(entry)
+}
+
+func (s *ObservedStorage) UpdateEntry(entry models.Entry) (err error) {
+	defer s.observe("update_entry", time.Now(), &err)
+	return s.backend.UpdateEntry(entry)
+}
+
+func (s *ObservedStorage) DeleteEntry(scope Scope, id string) (err error) {
+	defer s.observe("delete_entry", time.Now(), &err)
+	return s.backend.DeleteEntry(scope, id)
+}
+
+func (s *ObservedStorage) GetEntry(scope Scope, id string) (entry models.Entry, err error) {
+	defer s.observe("get_entry", time.Now(), &err)
+	return s.backend.GetEntry(scope, id)
+}
+
+// ForEachEntry reports the time taken by fn too, such as sending each entry
+// to a client, since reading and handling the entries are interleaved.
+func (s *ObservedStorage) ForEachEntry(scope Scope, fn func(models.Entry) error) (err error) {
+	defer s.observe("for_each_entry", time.Now(), &err)
+	return s.backend.ForEachEntry(scope, fn)
+}
+
+func (s *ObservedStorage) CreateNotebook(notebook models.Notebook) (err error) {
+	defer s.observe("create_notebook", time.Now(), &err)
+	return s.backend.CreateNotebook(notebook)
+}
+
+func (s *ObservedStorage) ListNotebooks(owner string) (notebooks []models.Notebook, err error) {
+	defer s.observe("list_notebooks", time.Now(), &err)
+	return s.backend.ListNotebooks(owner)
+}
+
+func (s *ObservedStorage) GetNotebook(owner, name string) (notebook models.Notebook, err error) {
+	defer s.observe("get_notebook", time.Now(), &err)
+	return s.backend.GetNotebook(owner, name)
+}
+
+func (s *ObservedStorage) RenameNotebook(owner, name, newName string) (err error) {
+	defer s.observe("rename_notebook", time.Now(), &err)
+	return s.backend.RenameNotebook(owner, name, newName)
+}
+
+func (s *ObservedStorage) DeleteNotebook(owner, name string) (err error) {
+	defer s.observe("delete_notebook", time.Now(), &err)
+	return s.backend.DeleteNotebook(owner, name)
+}
+
+func (s *ObservedStorage) MoveEntry(scope Scope, id, notebook string) (err error) {
+	defer s.observe("move_entry", time.Now(), &err)
+	return s.backend.MoveEntry(scope, id, notebook)
+}
+
+func (s *ObservedStorage) Ping(ctx context.Context) (err error) {
+	defer s.observe("ping", time.Now(), &err)
+	return s.backend.Ping(ctx)
+}
+
+// Close closes the backend
+func (s *ObservedStorage) Close() error {
+	return s.backend.Close()
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"journal/models"
+)
+
+// createNotebookTable creates the notebooks table if it doesn't exist
+func createNotebookTable(db *sql.DB) error {
+	query := `
+    CREATE TABLE IF NOT EXISTS notebooks (
+        id TEXT PRIMARY KEY,
+        owner TEXT NOT NULL DEFAULT '',
+        name TEXT NOT NULL,
+        created TIMESTAMP,
+        UNIQUE (owner, name)
+    );
+    `
+	_, err := db.Exec(query)
+	return err
+}
+
+// isUniqueViolation reports whether err is a SQLite unique constraint failure
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// CreateNotebook creates a new notebook in SQLite
+func (s *SQLiteStorage) CreateNotebook(notebook models.Notebook) error {
+	query := `
+	INSERT INTO notebooks (id, owner, name, created)
+	VALUES (?,?,?,?)
+	`
+	_, err := s.DB.Exec(query, notebook.ID, notebook.Owner, notebook.Name, notebook.Created)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// ListNotebooks loads the notebooks of an owner, sorted by name
+func (s *SQLiteStorage) ListNotebooks(owner string) ([]models.Notebook, error) {
+	query := `SELECT id, owner, name, created FROM notebooks WHERE owner = ? ORDER BY name`
+	rows, err := s.DB.Query(query, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notebooks []models.Notebook
+	for rows.Next() {
+		var notebook models.Notebook
+		if err := rows.Scan(&notebook.ID, &notebook.Owner, &notebook.Name, &notebook.Created); err != nil {
+			return nil, err
+		}
+		notebooks = append(notebooks, notebook)
+	}
+	return notebooks, rows.Err()
+}
+
+// GetNotebook loads a notebook by its name
+func (s *SQLiteStorage) GetNotebook(owner, name string) (models.Notebook, error) {
+	query := `SELECT id, owner, name, created FROM notebooks WHERE owner = ? AND name = ?`
+	var notebook models.Notebook
+	err := s.DB.QueryRow(query, owner, name).Scan(&notebook.ID, &notebook.Owner, &notebook.Name, &notebook.Created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notebook, ErrNotFound
+	}
+	return notebook, err
+}
+
+// RenameNotebook renames a notebook and refiles its entries under the new name
+func (s *SQLiteStorage) RenameNotebook(owner, name, newName string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE notebooks SET name = ? WHERE owner = ? AND name = ?`, newName, owner, name)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(result); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE journal_entries SET notebook = ? WHERE owner = ? AND notebook = ?`, newName, owner, name)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteNotebook deletes a notebook by its name
+func (s *SQLiteStorage) DeleteNotebook(owner, name string) error {
+	result, err := s.DB.Exec(`DELETE FROM notebooks WHERE owner = ? AND name = ?`, owner, name)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// MoveEntry files an entry within the scope in another notebook
+func (s *SQLiteStorage) MoveEntry(scope Scope, id, notebook string) error {
+	condition, args := scopeCondition(scope)
+	query := `UPDATE journal_entries SET notebook = ? WHERE id = ? AND ` + condition
+	result, err := s.DB.Exec(query, append([]any{notebook, id}, args...)...)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
@@ -1,26 +1,82 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	_ "github.com/mattn/go-sqlite3"
 	"journal/models"
+	"net/url"
+	"strconv"
+	"time"
 )
 
 type SQLiteStorage struct {
 	DB *sql.DB
 }
 
+// SQLiteOptions configures the connections to an SQLite database
+type SQLiteOptions struct {
+	// WAL turns on write-ahead logging, so reading doesn't wait for writing.
+	// The database stays in WAL mode for every program opening it.
+	WAL bool
+	// BusyTimeout is how long to wait for another connection or process to
+	// finish writing before failing with "database is locked"
+	BusyTimeout time.Duration
+	// MaxOpenConns limits the open connections; zero means no limit
+	MaxOpenConns int
+	// MaxIdleConns is how many unused connections are kept open
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections once they are this old; zero keeps them
+	ConnMaxLifetime time.Duration
+}
+
+// DefaultSQLiteOptions returns the options NewSQLiteStorage opens databases with
+func DefaultSQLiteOptions() SQLiteOptions {
+	return SQLiteOptions{
+		WAL:          true,
+		BusyTimeout:  5 * time.Second,
+		MaxIdleConns: 2,
+	}
+}
+
+// dsn returns the data source name opening dbFile with the options. They are
+// set through it rather than with PRAGMA statements so that every connection
+// in the pool gets them.
+func (options SQLiteOptions) dsn(dbFile string) string {
+	params := url.Values{}
+	params.Set("_busy_timeout", strconv.FormatInt(options.BusyTimeout.Milliseconds(), 10))
+	if options.WAL {
+		params.Set("_journal_mode", "WAL")
+	} else {
+		params.Set("_journal_mode", "DELETE")
+	}
+	return dbFile + "?" + params.Encode()
+}
+
 // NewSQLiteStorage initializes the SQLite database and returns a storage instance
 func NewSQLiteStorage(dbFile string) (*SQLiteStorage, error) {
-	db, err := sql.Open("sqlite3", dbFile)
+	return NewSQLiteStorageWithOptions(dbFile, DefaultSQLiteOptions())
+}
+
+// NewSQLiteStorageWithOptions is NewSQLiteStorage with the connections
+// configured by the options
+func NewSQLiteStorageWithOptions(dbFile string, options SQLiteOptions) (*SQLiteStorage, error) {
+	db, err := sql.Open("sqlite3", options.dsn(dbFile))
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(options.MaxOpenConns)
+	db.SetMaxIdleConns(options.MaxIdleConns)
+	db.SetConnMaxLifetime(options.ConnMaxLifetime)
 
 	// Create journal_entries table if it doesn't exist
 	query := `
     CREATE TABLE IF NOT EXISTS journal_entries (
         id TEXT PRIMARY KEY,
+        owner TEXT NOT NULL DEFAULT '',
+        notebook TEXT NOT NULL DEFAULT '',
         title TEXT,
         content TEXT,
         created TIMESTAMP,
@@ -32,17 +88,108 @@ func NewSQLiteStorage(dbFile string) (*SQLiteStorage, error) {
 		return nil, err
 	}
 
+	// Databases created before accounts existed have no owner column
+	if err := addColumnIfMissing(db, "journal_entries", "owner", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, err
+	}
+	// ...and no notebook column before notebooks existed
+	if err := addColumnIfMissing(db, "journal_entries", "notebook", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, err
+	}
+	if err := createUserTables(db); err != nil {
+		return nil, err
+	}
+	if err := createNotebookTable(db); err != nil {
+		return nil, err
+	}
+	if err := createWebhookTables(db); err != nil {
+		return nil, err
+	}
+
 	return &SQLiteStorage{DB: db}, nil
 }
 
+// Ping checks that the database file can still be read
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	// The driver's own ping doesn't touch the file, so run a query instead
+	var version int
+	return s.DB.QueryRowContext(ctx, `PRAGMA schema_version`).Scan(&version)
+}
+
+// CountEntries returns how many entries the database holds
+func (s *SQLiteStorage) CountEntries(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM journal_entries`).Scan(&count)
+	return count, err
+}
+
+// ListOwners returns the distinct owners of the entries, sorted
+func (s *SQLiteStorage) ListOwners() ([]string, error) {
+	rows, err := s.DB.Query(`SELECT DISTINCT owner FROM journal_entries ORDER BY owner`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var owners []string
+	for rows.Next() {
+		var owner string
+		if err := rows.Scan(&owner); err != nil {
+			return nil, err
+		}
+		owners = append(owners, owner)
+	}
+	return owners, rows.Err()
+}
+
+// Close closes the database, waiting for the queries in progress to finish
+func (s *SQLiteStorage) Close() error {
+	return s.DB.Close()
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already there
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+	return err
+}
+
+// scopeCondition returns the WHERE condition matching the entries within the scope
+func scopeCondition(scope Scope) (string, []any) {
+	if scope.Notebook == "" {
+		return "owner = ?", []any{scope.Owner}
+	}
+	return "owner = ? AND notebook = ?", []any{scope.Owner, scope.Notebook}
+}
+
 // GetEntry loads a journal entry from the SQLite database
-func (s *SQLiteStorage) GetEntry(id string) (models.Entry, error) {
+func (s *SQLiteStorage) GetEntry(scope Scope, id string) (models.Entry, error) {
+	condition, args := scopeCondition(scope)
 	query := `
-	SELECT id, title, content, created, updated FROM journal_entries WHERE id = ?
-	`
-	row := s.DB.QueryRow(query, id)
+	SELECT id, owner, notebook, title, content, created, updated FROM journal_entries WHERE id = ? AND ` + condition
+	row := s.DB.QueryRow(query, append([]any{id}, args...)...)
 	var entry models.Entry
-	err := row.Scan(&entry.ID, &entry.Title, &entry.Content, &entry.Created, &entry.Updated)
+	err := row.Scan(&entry.ID, &entry.Owner, &entry.Notebook, &entry.Title, &entry.Content, &entry.Created, &entry.Updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entry, ErrNotFound
+	}
 	if err != nil {
 		return entry, err
 	}
@@ -50,55 +197,93 @@ func (s *SQLiteStorage) GetEntry(id string) (models.Entry, error) {
 }
 
 // LoadEntries loads journal entries from the SQLite database
-func (s *SQLiteStorage) LoadEntries() ([]models.Entry, error) {
-	query := `SELECT id, title, content, created, updated FROM journal_entries `
-	rows, err := s.DB.Query(query)
+func (s *SQLiteStorage) LoadEntries(scope Scope) ([]models.Entry, error) {
+	var entries []models.Entry
+	err := s.ForEachEntry(scope, func(entry models.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return entries, nil
+}
+
+// ForEachEntry reads the journal entries from the SQLite database one row at a time
+func (s *SQLiteStorage) ForEachEntry(scope Scope, fn func(models.Entry) error) error {
+	condition, args := scopeCondition(scope)
+	query := `SELECT id, owner, notebook, title, content, created, updated FROM journal_entries WHERE ` + condition
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
-	var entries []models.Entry
 	for rows.Next() {
 		var entry models.Entry
-		err := rows.Scan(&entry.ID, &entry.Title, &entry.Content, &entry.Created, &entry.Updated)
+		err := rows.Scan(&entry.ID, &entry.Owner, &entry.Notebook, &entry.Title, &entry.Content, &entry.Created, &entry.Updated)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
 		}
-		entries = append(entries, entry)
 	}
 
-	return entries, nil
+	return rows.Err()
 }
 
-// SaveEntries saves the journal entries to the SQLite database (insert or update)
+// SaveEntries saves the journal entries to the SQLite database (insert or
+// update). An ID used by another owner's entry is a conflict, and leaves none
+// of the entries saved.
 func (s *SQLiteStorage) SaveEntries(entries []models.Entry) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO journal_entries (id, owner, notebook, title, content, created, updated)
+	VALUES (?,?,?,?,?,?,?)
+	ON CONFLICT(id) DO UPDATE SET 
+		notebook=excluded.notebook,
+		title=excluded.title,
+		content=excluded.content,
+		updated=excluded.updated
+	WHERE owner=excluded.owner;
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
 	for _, entry := range entries {
-		query := `
-		INSERT INTO journal_entries (id, title, content, created, updated)
-		VALUES (?,?,?,?,?)
-		ON CONFLICT(id) DO UPDATE SET 
-			title=excluded.title,
-			content=excluded.content,
-			updated=excluded.updated;
-		`
-		_, err := s.DB.Exec(query, entry.ID, entry.Title, entry.Created, entry.Created, entry.Updated)
+		result, err := stmt.Exec(entry.ID, entry.Owner, entry.Notebook, entry.Title, entry.Content, entry.Created, entry.Updated)
 		if err != nil {
-			return err
+			return fmt.Errorf("saving entry %s: %w", entry.ID, err)
+		}
+		// The update is skipped when another owner has an entry with the ID
+		if n, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("saving entry %s: %w", entry.ID, err)
+		} else if n == 0 {
+			return fmt.Errorf("saving entry %s: %w", entry.ID, ErrConflict)
 		}
 	}
-	return nil
+	return tx.Commit()
 }
 
 // CreateEntry creates a new journal entry in SQLite
 func (s *SQLiteStorage) CreateEntry(entry models.Entry) error {
 	query := `
-	INSERT INTO journal_entries (id, title, content, created, updated)
-	VALUES (?,?,?,?,?)
+	INSERT INTO journal_entries (id, owner, notebook, title, content, created, updated)
+	VALUES (?,?,?,?,?,?,?)
 	`
 
-	_, err := s.DB.Exec(query, entry.ID, entry.Title, entry.Content, entry.Created, entry.Updated)
-
+	_, err := s.DB.Exec(query, entry.ID, entry.Owner, entry.Notebook, entry.Title, entry.Content, entry.Created, entry.Updated)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
 	return err
 }
 
@@ -107,17 +292,35 @@ func (s *SQLiteStorage) UpdateEntry(entry models.Entry) error {
 	query := `
 	UPDATE journal_entries 
 	SET title = ?, content = ?, updated = ?
-	WHERE id = ?
+	WHERE id = ? AND owner = ?
 	`
-	_, err := s.DB.Exec(query, entry.Title, entry.Content, entry.Updated, entry.ID)
-	return err
+	result, err := s.DB.Exec(query, entry.Title, entry.Content, entry.Updated, entry.ID, entry.Owner)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
 }
 
 // DeleteEntry deletes a journal entry from SQLite by its ID
-func (s *SQLiteStorage) DeleteEntry(id string) error {
+func (s *SQLiteStorage) DeleteEntry(scope Scope, id string) error {
+	condition, args := scopeCondition(scope)
 	query := `
-	DELETE FROM journal_entries WHERE id = ?
-	`
-	_, err := s.DB.Exec(query, id)
-	return err
+	DELETE FROM journal_entries WHERE id = ? AND ` + condition
+	result, err := s.DB.Exec(query, append([]any{id}, args...)...)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// checkAffected reports ErrNotFound when a statement matched no rows
+func checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newTestSQLite opens a SQLite database in a temporary directory
+func newTestSQLite(t *testing.T) *SQLiteStorage {
+	t.Helper()
+	store, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteCreateEntryConflicts(t *testing.T) {
+	testCreateEntryConflicts(t, newTestSQLite(t))
+}
+
+func TestSQLiteSaveEntriesConflicts(t *testing.T) {
+	testSaveEntriesConflicts(t, newTestSQLite(t), true)
+}
+
+func TestSQLiteSaveEntriesUpserts(t *testing.T) {
+	testSaveEntriesUpserts(t, newTestSQLite(t))
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"journal/models"
+	"time"
+)
+
+// createUserTables creates the users and sessions tables if they don't exist
+func createUserTables(db *sql.DB) error {
+	query := `
+    CREATE TABLE IF NOT EXISTS users (
+        id TEXT PRIMARY KEY,
+        username TEXT NOT NULL UNIQUE,
+        password_hash TEXT NOT NULL,
+        created TIMESTAMP
+    );
+    CREATE TABLE IF NOT EXISTS sessions (
+        token_hash TEXT PRIMARY KEY,
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        created TIMESTAMP,
+        expires TIMESTAMP
+    );
+    CREATE TABLE IF NOT EXISTS api_tokens (
+        id TEXT PRIMARY KEY,
+        user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        name TEXT NOT NULL,
+        token_hash TEXT NOT NULL UNIQUE,
+        scope TEXT NOT NULL,
+        created TIMESTAMP,
+        last_used TIMESTAMP
+    );
+    `
+	_, err := db.Exec(query)
+	return err
+}
+
+// CreateUser creates a new user account in SQLite
+func (s *SQLiteStorage) CreateUser(user models.User) error {
+	query := `
+	INSERT INTO users (id, username, password_hash, created)
+	VALUES (?,?,?,?)
+	`
+	_, err := s.DB.Exec(query, user.ID, user.Username, user.PasswordHash, user.Created)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// GetUser loads a user account by its ID
+func (s *SQLiteStorage) GetUser(id string) (models.User, error) {
+	query := `SELECT id, username, password_hash, created FROM users WHERE id = ?`
+	return scanUser(s.DB.QueryRow(query, id))
+}
+
+// GetUserByUsername loads a user account by its username
+func (s *SQLiteStorage) GetUserByUsername(username string) (models.User, error) {
+	query := `SELECT id, username, password_hash, created FROM users WHERE username = ?`
+	return scanUser(s.DB.QueryRow(query, username))
+}
+
+func scanUser(row *sql.Row) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+// CountUsers returns the number of user accounts
+func (s *SQLiteStorage) CountUsers() (int, error) {
+	var count int
+	err := s.DB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+	return count, err
+}
+
+// ClaimEntries assigns every entry without an owner to the given user if
+// they were the first user created. The check and the update are one
+// statement, so a user signing up at the same time can't claim them as well.
+func (s *SQLiteStorage) ClaimEntries(owner string) error {
+	query := `
+	UPDATE journal_entries SET owner = ?
+	WHERE owner = '' AND ? = (SELECT id FROM users ORDER BY rowid LIMIT 1)
+	`
+	_, err := s.DB.Exec(query, owner, owner)
+	return err
+}
+
+// CreateSession stores a new login session
+func (s *SQLiteStorage) CreateSession(session models.Session) error {
+	query := `
+	INSERT INTO sessions (token_hash, user_id, created, expires)
+	VALUES (?,?,?,?)
+	`
+	_, err := s.DB.Exec(query, session.TokenHash, session.UserID, session.Created, session.Expires)
+	return err
+}
+
+// GetSession loads a login session by the hash of its token
+func (s *SQLiteStorage) GetSession(tokenHash string) (models.Session, error) {
+	query := `SELECT token_hash, user_id, created, expires FROM sessions WHERE token_hash = ?`
+	var session models.Session
+	err := s.DB.QueryRow(query, tokenHash).Scan(&session.TokenHash, &session.UserID, &session.Created, &session.Expires)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, ErrNotFound
+	}
+	return session, err
+}
+
+// DeleteSession removes a login session
+func (s *SQLiteStorage) DeleteSession(tokenHash string) error {
+	_, err := s.DB.Exec(`DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	return err
+}
+
+// CreateToken stores a new API token
+func (s *SQLiteStorage) CreateToken(token models.Token) error {
+	query := `
+	INSERT INTO api_tokens (id, user_id, name, token_hash, scope, created, last_used)
+	VALUES (?,?,?,?,?,?,?)
+	`
+	_, err := s.DB.Exec(query, token.ID, token.UserID, token.Name, token.Hash, token.Scope, token.Created, token.LastUsed)
+	return err
+}
+
+// ListTokens loads the API tokens of a user, newest first
+func (s *SQLiteStorage) ListTokens(userID string) ([]models.Token, error) {
+	query := `
+	SELECT id, user_id, name, token_hash, scope, created, last_used FROM api_tokens
+	WHERE user_id = ? ORDER BY created DESC
+	`
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.Token
+	for rows.Next() {
+		var token models.Token
+		err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.Hash, &token.Scope, &token.Created, &token.LastUsed)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetTokenByHash loads an API token by the hash of its secret
+func (s *SQLiteStorage) GetTokenByHash(hash string) (models.Token, error) {
+	query := `SELECT id, user_id, name, token_hash, scope, created, last_used FROM api_tokens WHERE token_hash = ?`
+	var token models.Token
+	err := s.DB.QueryRow(query, hash).Scan(&token.ID, &token.UserID, &token.Name, &token.Hash, &token.Scope, &token.Created, &token.LastUsed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return token, ErrNotFound
+	}
+	return token, err
+}
+
+// TouchToken records when an API token was last used
+func (s *SQLiteStorage) TouchToken(id string, used time.Time) error {
+	_, err := s.DB.Exec(`UPDATE api_tokens SET last_used = ? WHERE id = ?`, used, id)
+	return err
+}
+
+// DeleteToken revokes one of a user's API tokens
+func (s *SQLiteStorage) DeleteToken(userID, id string) error {
+	result, err := s.DB.Exec(`DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"journal/models"
+	"testing"
+	"time"
+)
+
+func TestClaimEntriesOnlyForFirstUser(t *testing.T) {
+	store := newTestSQLite(t)
+	if err := store.CreateEntry(models.Entry{ID: "before-accounts", Title: "Title", Content: "Content"}); err != nil {
+		t.Fatal(err)
+	}
+	// Both users counted no users before either was created
+	for _, id := range []string{"first", "second"} {
+		if err := store.CreateUser(models.User{ID: id, Username: id, PasswordHash: "hash", Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.ClaimEntries("second"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetEntry(Scope{}, "before-accounts"); err != nil {
+		t.Errorf("got %v, want the second user's claim to leave the entry unowned", err)
+	}
+	if err := store.ClaimEntries("first"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetEntry(Scope{Owner: "first"}, "before-accounts"); err != nil {
+		t.Errorf("got %v, want the entry claimed by the first user", err)
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"journal/models"
+	"strings"
+)
+
+// createWebhookTables creates the webhooks and dead letter tables if they don't exist
+func createWebhookTables(db *sql.DB) error {
+	query := `
+    CREATE TABLE IF NOT EXISTS webhooks (
+        id TEXT PRIMARY KEY,
+        owner TEXT NOT NULL DEFAULT '',
+        url TEXT NOT NULL,
+        events TEXT NOT NULL DEFAULT '',
+        secret TEXT NOT NULL,
+        created TIMESTAMP
+    );
+    CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+        id TEXT PRIMARY KEY,
+        webhook_id TEXT NOT NULL,
+        owner TEXT NOT NULL DEFAULT '',
+        url TEXT NOT NULL,
+        event TEXT NOT NULL,
+        payload TEXT NOT NULL,
+        attempts INTEGER NOT NULL,
+        error TEXT NOT NULL,
+        created TIMESTAMP
+    );
+    `
+	_, err := db.Exec(query)
+	return err
+}
+
+// joinEvents stores the event types of a webhook as a comma-separated list
+func joinEvents(events []string) string {
+	return strings.Join(events, ",")
+}
+
+func splitEvents(events string) []string {
+	if events == "" {
+		return nil
+	}
+	return strings.Split(events, ",")
+}
+
+// CreateWebhook stores a new webhook
+func (s *SQLiteStorage) CreateWebhook(webhook models.Webhook) error {
+	query := `
+	INSERT INTO webhooks (id, owner, url, events, secret, created)
+	VALUES (?,?,?,?,?,?)
+	`
+	_, err := s.DB.Exec(query, webhook.ID, webhook.Owner, webhook.URL, joinEvents(webhook.Events), webhook.Secret, webhook.Created)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// ListWebhooks loads the webhooks of a user, oldest first
+func (s *SQLiteStorage) ListWebhooks(owner string) ([]models.Webhook, error) {
+	query := `SELECT id, owner, url, events, secret, created FROM webhooks WHERE owner = ? ORDER BY created`
+	rows, err := s.DB.Query(query, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook loads one of a user's webhooks
+func (s *SQLiteStorage) GetWebhook(owner, id string) (models.Webhook, error) {
+	query := `SELECT id, owner, url, events, secret, created FROM webhooks WHERE id = ? AND owner = ?`
+	webhook, err := scanWebhook(s.DB.QueryRow(query, id, owner))
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhook, ErrNotFound
+	}
+	return webhook, err
+}
+
+// scanWebhook reads a webhook from a row of *sql.Row or *sql.Rows
+func scanWebhook(row interface{ Scan(...any) error }) (models.Webhook, error) {
+	var webhook models.Webhook
+	var events string
+	err := row.Scan(&webhook.ID, &webhook.Owner, &webhook.URL, &events, &webhook.Secret, &webhook.Created)
+	webhook.Events = splitEvents(events)
+	return webhook, err
+}
+
+// DeleteWebhook removes one of a user's webhooks
+func (s *SQLiteStorage) DeleteWebhook(owner, id string) error {
+	result, err := s.DB.Exec(`DELETE FROM webhooks WHERE id = ? AND owner = ?`, id, owner)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// AddDeadLetter records a webhook delivery that failed every attempt
+func (s *SQLiteStorage) AddDeadLetter(letter models.DeadLetter) error {
+	query := `
+	INSERT INTO webhook_dead_letters (id, webhook_id, owner, url, event, payload, attempts, error, created)
+	VALUES (?,?,?,?,?,?,?,?,?)
+	`
+	_, err := s.DB.Exec(query, letter.ID, letter.WebhookID, letter.Owner, letter.URL, letter.Event, letter.Payload, letter.Attempts, letter.Error, letter.Created)
+	return err
+}
+
+// ListDeadLetters loads the failed deliveries of a user's webhooks, newest first
+func (s *SQLiteStorage) ListDeadLetters(owner string) ([]models.DeadLetter, error) {
+	query := `
+	SELECT id, webhook_id, owner, url, event, payload, attempts, error, created FROM webhook_dead_letters
+	WHERE owner = ? ORDER BY created DESC
+	`
+	rows, err := s.DB.Query(query, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []models.DeadLetter
+	for rows.Next() {
+		var letter models.DeadLetter
+		err := rows.Scan(&letter.ID, &letter.WebhookID, &letter.Owner, &letter.URL, &letter.Event, &letter.Payload, &letter.Attempts, &letter.Error, &letter.Created)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
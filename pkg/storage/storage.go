@@ -1,15 +1,131 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"journal/models"
+	"time"
 )
 
+// ErrNotFound is returned when the requested record does not exist in the store
+var ErrNotFound = errors.New("record does not exist")
+
+// ErrConflict is returned when a record clashes with an existing one, such as a taken username
+var ErrConflict = errors.New("record already exists")
+
+// Scope restricts entry operations to the entries visible to a single owner
+type Scope struct {
+	Owner    string // ID of the user owning the entries; empty for entries created without an account
+	Notebook string // Name of the notebook holding the entries; empty for every notebook
+}
+
 // Storage interface defines methods for storing journal entries
 type Storage interface {
-	LoadEntries() ([]models.Entry, error)
+	LoadEntries(scope Scope) ([]models.Entry, error)
 	SaveEntries(entries []models.Entry) error
 	CreateEntry(entry models.Entry) error
 	UpdateEntry(entry models.Entry) error
-	DeleteEntry(id string) error
-	GetEntry(id string) (models.Entry, error)
+	DeleteEntry(scope Scope, id string) error
+	GetEntry(scope Scope, id string) (models.Entry, error)
+	// ForEachEntry calls fn with every entry within the scope as it is read,
+	// without loading them all in memory. It stops at the first error fn returns.
+	ForEachEntry(scope Scope, fn func(models.Entry) error) error
+	NotebookStorage
+	// Ping checks that the database can be reached
+	Ping(ctx context.Context) error
+	// Close releases the connection to the database; the storage can't be
+	// used afterwards
+	Close() error
+}
+
+// NotebookStorage interface defines methods for storing notebooks and filing entries in them
+type NotebookStorage interface {
+	CreateNotebook(notebook models.Notebook) error
+	ListNotebooks(owner string) ([]models.Notebook, error)
+	GetNotebook(owner, name string) (models.Notebook, error)
+	// RenameNotebook renames a notebook along with the notebook of its entries
+	RenameNotebook(owner, name, newName string) error
+	DeleteNotebook(owner, name string) error
+	// MoveEntry files an entry in another notebook; an empty notebook takes it out of any
+	MoveEntry(scope Scope, id, notebook string) error
+}
+
+// ChangeType says how an entry changed
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// Change is a change to an entry reported by a Watcher
+type Change struct {
+	Type  ChangeType
+	Entry models.Entry // The entry after the change; only ID, Owner and Notebook are set for deleted entries
+	Time  time.Time    // When the change was made
+}
+
+// ErrWatchUnsupported is returned by WatchEntries when the database can't report changes
+var ErrWatchUnsupported = errors.New("the database can't report changes")
+
+// Watcher is implemented by backends that can report changes to entries made
+// by any process, such as another server sharing the database.
+type Watcher interface {
+	// WatchEntries calls fn with every change to the entries until ctx is
+	// done. The name identifies the watcher, so after a restart it resumes
+	// from the last change it reported. It calls watching with true once
+	// changes are being followed, and with false while it can't follow them,
+	// such as while it retries after a failure.
+	WatchEntries(ctx context.Context, name string, watching func(bool), fn func(Change)) error
+}
+
+// OwnerLister is implemented by backends that can list whose entries they
+// hold, for work that must reach every entry, such as re-encrypting them.
+type OwnerLister interface {
+	// ListOwners returns the owners of the entries, the empty owner of
+	// entries created without an account included
+	ListOwners() ([]string, error)
+}
+
+// EntryCounter is implemented by backends that can cheaply count the entries
+// they hold, such as for monitoring.
+type EntryCounter interface {
+	// CountEntries returns how many entries there are across every owner and notebook
+	CountEntries(ctx context.Context) (int64, error)
+}
+
+// UserStorage interface defines methods for storing user accounts and their sessions
+type UserStorage interface {
+	CreateUser(user models.User) error
+	GetUser(id string) (models.User, error)
+	GetUserByUsername(username string) (models.User, error)
+	CountUsers() (int, error)
+	// ClaimEntries assigns every entry without an owner to the given user
+	// if they are the first user. Only the first user's claim takes effect,
+	// even when several users sign up at the same time.
+	ClaimEntries(owner string) error
+
+	CreateSession(session models.Session) error
+	GetSession(tokenHash string) (models.Session, error)
+	DeleteSession(tokenHash string) error
+
+	CreateToken(token models.Token) error
+	ListTokens(userID string) ([]models.Token, error)
+	GetTokenByHash(hash string) (models.Token, error)
+	TouchToken(id string, used time.Time) error
+	DeleteToken(userID, id string) error
+}
+
+// WebhookStorage interface defines methods for storing webhooks and the deliveries that failed
+type WebhookStorage interface {
+	CreateWebhook(webhook models.Webhook) error
+	// ListWebhooks loads the webhooks of a user, oldest first
+	ListWebhooks(owner string) ([]models.Webhook, error)
+	GetWebhook(owner, id string) (models.Webhook, error)
+	DeleteWebhook(owner, id string) error
+
+	AddDeadLetter(letter models.DeadLetter) error
+	// ListDeadLetters loads the failed deliveries of a user's webhooks, newest first
+	ListDeadLetters(owner string) ([]models.DeadLetter, error)
 }
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"errors"
+	"journal/models"
+	"testing"
+	"time"
+)
+
+// The tests every backend has to pass, run by the backends' own tests
+
+func testCreateEntryConflicts(t *testing.T, store Storage) {
+	entry := models.Entry{ID: "taken", Owner: "first", Title: "Title", Content: "Content", Created: time.Now()}
+	if err := store.CreateEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	for _, owner := range []string{"first", "second"} {
+		entry.Owner = owner
+		if err := store.CreateEntry(entry); !errors.Is(err, ErrConflict) {
+			t.Errorf("creating the entry again as %s: got %v, want %v", owner, err, ErrConflict)
+		}
+	}
+}
+
+// testSaveEntriesConflicts checks a conflict fails the save, and with atomic
+// saves that none of the entries were saved
+func testSaveEntriesConflicts(t *testing.T, store Storage, atomic bool) {
+	if err := store.CreateEntry(models.Entry{ID: "taken", Owner: "first", Title: "First's", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	err := store.SaveEntries([]models.Entry{
+		{ID: "new", Owner: "second", Title: "New", Created: time.Now()},
+		{ID: "taken", Owner: "second", Title: "Second's", Created: time.Now()},
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("got %v, want %v", err, ErrConflict)
+	}
+	if entry, err := store.GetEntry(Scope{Owner: "first"}, "taken"); err != nil || entry.Title != "First's" {
+		t.Errorf("got %+v, %v, want the first owner's entry unchanged", entry, err)
+	}
+	if !atomic {
+		return
+	}
+	if _, err := store.GetEntry(Scope{Owner: "second"}, "new"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want the entry saved before the conflict rolled back", err)
+	}
+}
+
+func testSaveEntriesUpserts(t *testing.T, store Storage) {
+	if err := store.CreateEntry(models.Entry{ID: "existing", Owner: "owner", Title: "Old", Created: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	err := store.SaveEntries([]models.Entry{
+		{ID: "existing", Owner: "owner", Title: "Updated", Created: time.Now()},
+		{ID: "new", Owner: "owner", Title: "New", Created: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, title := range map[string]string{"existing": "Updated", "new": "New"} {
+		if entry, err := store.GetEntry(Scope{Owner: "owner"}, id); err != nil || entry.Title != title {
+			t.Errorf("got %+v, %v, want %s titled %q", entry, err, id, title)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"bytes"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"html/template"
+	"regexp"
+)
+
+// highlightStyle is the chroma style used for fenced code blocks.
+const highlightStyle = "github"
+
+// markdown converts entry content to HTML. Code blocks are highlighted with
+// CSS classes rather than inline styles so the sanitizer can stay strict.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM, // Tables, strikethrough, autolinks and task lists
+		highlighting.NewHighlighting(
+			highlighting.WithStyle(highlightStyle),
+			highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+		),
+	),
+)
+
+// markdownPolicy is the HTML allowed in rendered entries: the usual user
+// generated content, plus chroma's class names and disabled task-list checkboxes.
+var markdownPolicy = func() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-zA-Z0-9 _-]+$`)).OnElements("pre", "code", "span")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	return policy
+}()
+
+// RenderMarkdown renders Markdown content to sanitized HTML safe to embed in a template
+func RenderMarkdown(content string) template.HTML {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(content), &buf); err != nil {
+		// Fall back to the escaped source rather than dropping the content
+		return template.HTML("<pre>" + template.HTMLEscapeString(content) + "</pre>")
+	}
+	return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes()))
+}
+
+// HighlightCSS returns the stylesheet for the classes emitted in highlighted code blocks
+func HighlightCSS() ([]byte, error) {
+	var buf bytes.Buffer
+	formatter := chromahtml.New(chromahtml.WithClasses(true))
+	if err := formatter.WriteCSS(&buf, styles.Get(highlightStyle)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
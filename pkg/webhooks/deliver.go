@@ -0,0 +1,209 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"journal/pkg/journal"
+	"journal/pkg/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// deliveryTimeout is how long a receiver may take to answer
+	deliveryTimeout = 10 * time.Second
+	// maxAttempts is how often a delivery is tried before it goes to the dead letters
+	maxAttempts = 6
+	// firstRetryDelay is the wait before the first retry; it doubles for every
+	// further one, so all attempts take about half a minute
+	firstRetryDelay = time.Second
+)
+
+// TestEvent is the type of the event sent by Test
+const TestEvent = "test"
+
+// Headers sent with every delivery
+const (
+	EventHeader     = "X-Journal-Event"     // Type of the event
+	DeliveryHeader  = "X-Journal-Delivery"  // Unique ID of the delivery, the same for every attempt
+	TimestampHeader = "X-Journal-Timestamp" // Unix time the delivery was signed at
+	// SignatureHeader holds "sha256=" and the hex HMAC-SHA256 of the timestamp,
+	// a dot and the body, keyed with the webhook's secret
+	SignatureHeader = "X-Journal-Signature"
+)
+
+// delivery is an event on its way to a webhook
+type delivery struct {
+	id      string
+	webhook models.Webhook
+	event   string
+	payload []byte
+}
+
+// Sign returns the signature of a delivery sent at the timestamp, as found in
+// the SignatureHeader, for receivers to compare against.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run delivers the events published on the bus to the webhooks of the
+// entries' owners until ctx is done. Deliveries happen in the background and
+// are retried with exponential backoff; those failing every attempt are
+// recorded as dead letters. Once ctx is done, Run returns when the deliveries
+// under way have given up and been recorded, so the storage can be closed.
+func (webhooks *Webhooks) Run(ctx context.Context, bus *journal.Bus) {
+	defer webhooks.deliveries.Wait()
+	events, unsubscribe := bus.SubscribeAll()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			// Looking the webhooks up in the background keeps the subscription
+			// from falling behind and missing events
+			webhooks.deliveries.Add(1)
+			go func() {
+				defer webhooks.deliveries.Done()
+				webhooks.dispatch(ctx, event)
+			}()
+		}
+	}
+}
+
+// dispatch sends the event to every webhook of its owner that wants it
+func (webhooks *Webhooks) dispatch(ctx context.Context, event journal.Event) {
+	logger := slog.With("event", event.Type, "entry", event.Entry.ID)
+	hooks, err := webhooks.storage.ListWebhooks(event.Entry.Owner)
+	if err != nil {
+		logger.Error("Loading the webhooks for an event failed", "err", err)
+		return
+	}
+	var payload []byte
+	for _, webhook := range hooks {
+		if !wants(webhook, event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(v1.FromEvent(string(event.Type), event.Entry, event.Time)); err != nil {
+				logger.Error("Encoding a webhook event failed", "err", err)
+				return
+			}
+		}
+		d := delivery{id: utils.GenerateID(), webhook: webhook, event: string(event.Type), payload: payload}
+		webhooks.deliveries.Add(1)
+		go func() {
+			defer webhooks.deliveries.Done()
+			webhooks.deliver(ctx, d)
+		}()
+	}
+}
+
+// deliver posts the delivery until the receiver accepts it, it refuses it for
+// good or maxAttempts is reached, and then records it as a dead letter.
+func (webhooks *Webhooks) deliver(ctx context.Context, d delivery) {
+	delay := webhooks.retryDelay
+	var err error
+	attempts := 0
+	for attempts < maxAttempts {
+		attempts++
+		var retry bool
+		if retry, err = webhooks.post(ctx, d); err == nil || !retry {
+			break
+		}
+		if attempts == maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			// Shutting down; the delivery ends up with the dead letters so it isn't lost silently
+			err = fmt.Errorf("%w (gave up on shutdown)", err)
+			attempts = maxAttempts
+		case <-time.After(delay):
+			delay *= 2
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	logger := slog.With("webhook", d.webhook.ID, "event", d.event, "delivery", d.id)
+	logger.Warn("Delivering a webhook event failed", "attempts", attempts, "err", err)
+	letter := models.DeadLetter{
+		ID:        d.id,
+		WebhookID: d.webhook.ID,
+		Owner:     d.webhook.Owner,
+		URL:       d.webhook.URL,
+		Event:     d.event,
+		Payload:   string(d.payload),
+		Attempts:  attempts,
+		Error:     err.Error(),
+		Created:   time.Now(),
+	}
+	if err := webhooks.storage.AddDeadLetter(letter); err != nil {
+		logger.Error("Recording a dead letter failed", "err", err)
+	}
+}
+
+// post makes a single delivery attempt. It reports whether a failed attempt
+// is worth retrying: network errors, server errors, 408 and 429 are, other
+// client errors and refused addresses are not.
+func (webhooks *Webhooks) post(ctx context.Context, d delivery) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, "POST", d.webhook.URL, bytes.NewReader(d.payload))
+	if err != nil {
+		return false, err
+	}
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "journal-webhooks")
+	request.Header.Set(EventHeader, d.event)
+	request.Header.Set(DeliveryHeader, d.id)
+	request.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(SignatureHeader, Sign(d.webhook.Secret, timestamp, d.payload))
+
+	response, err := webhooks.client.Do(request)
+	if err != nil {
+		return !errors.Is(err, errPrivateAddress), err
+	}
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+	response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("receiver answered %s", response.Status)
+	retry := response.StatusCode >= 500 ||
+		response.StatusCode == http.StatusRequestTimeout ||
+		response.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
+
+// Test sends a single test event to one of the user's webhooks and returns
+// the delivery error, if any. It isn't retried or recorded as a dead letter.
+func (webhooks *Webhooks) Test(ctx context.Context, owner, id string) error {
+	webhook, err := webhooks.Get(owner, id)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(v1.Event{Type: TestEvent, Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = webhooks.post(ctx, delivery{id: utils.GenerateID(), webhook: webhook, event: TestEvent, payload: payload})
+	return err
+}
@@ -0,0 +1,209 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"journal/models"
+	"journal/pkg/journal"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver is an httptest server answering each delivery attempt with the
+// next of its statuses, and then with the last one
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	times    []time.Time
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+	rec := &receiver{statuses: statuses}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		status := rec.statuses[min(len(rec.requests), len(rec.statuses)-1)]
+		rec.requests = append(rec.requests, r)
+		rec.bodies = append(rec.bodies, body)
+		rec.times = append(rec.times, time.Now())
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (rec *receiver) attempts() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.requests)
+}
+
+// deliverTo creates a webhook for the receiver and delivers a created event to it
+func deliverTo(t *testing.T, webhooks *Webhooks, rec *receiver) models.Webhook {
+	t.Helper()
+	webhook, err := webhooks.Create("owner", rec.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhooks.deliver(context.Background(), delivery{id: "delivery", webhook: webhook, event: "created", payload: []byte(`{"type":"created"}`)})
+	return webhook
+}
+
+func TestDeliverySignature(t *testing.T) {
+	webhooks, db := newTestWebhooks(t)
+	rec := newReceiver(t, http.StatusNoContent)
+	webhook := deliverTo(t, webhooks, rec)
+
+	if rec.attempts() != 1 {
+		t.Fatalf("got %d attempts, want 1", rec.attempts())
+	}
+	request := rec.requests[0]
+	timestamp, err := strconv.ParseInt(request.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("bad timestamp header: %v", err)
+	}
+	if got, want := request.Header.Get(SignatureHeader), Sign(webhook.Secret, timestamp, rec.bodies[0]); got != want {
+		t.Errorf("got signature %s, want %s", got, want)
+	}
+	if got := request.Header.Get(SignatureHeader); got == Sign("whsec_other", timestamp, rec.bodies[0]) {
+		t.Error("the signature doesn't depend on the secret")
+	}
+	if request.Header.Get(EventHeader) != "created" || request.Header.Get(DeliveryHeader) != "delivery" {
+		t.Errorf("got event %q and delivery %q headers", request.Header.Get(EventHeader), request.Header.Get(DeliveryHeader))
+	}
+
+	letters, err := db.ListDeadLetters("owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 0 {
+		t.Errorf("an accepted delivery left %d dead letters", len(letters))
+	}
+}
+
+func TestDeliveryBacksOff(t *testing.T) {
+	webhooks, db := newTestWebhooks(t)
+	rec := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusOK)
+	deliverTo(t, webhooks, rec)
+
+	if rec.attempts() != 4 {
+		t.Fatalf("got %d attempts, want 4", rec.attempts())
+	}
+	delay := webhooks.retryDelay
+	for i := 1; i < len(rec.times); i++ {
+		if waited := rec.times[i].Sub(rec.times[i-1]); waited < delay {
+			t.Errorf("retry %d came after %s, want at least %s", i, waited, delay)
+		}
+		delay *= 2
+	}
+	for _, request := range rec.requests {
+		if request.Header.Get(DeliveryHeader) != "delivery" {
+			t.Errorf("a retry has delivery ID %q, want the same on every attempt", request.Header.Get(DeliveryHeader))
+		}
+	}
+	if letters, _ := db.ListDeadLetters("owner"); len(letters) != 0 {
+		t.Errorf("a delivery accepted on retry left %d dead letters", len(letters))
+	}
+}
+
+func TestDeliveryDeadLetters(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		{"retried", http.StatusBadGateway, maxAttempts},
+		{"refused", http.StatusBadRequest, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			webhooks, db := newTestWebhooks(t)
+			rec := newReceiver(t, test.status)
+			webhook := deliverTo(t, webhooks, rec)
+
+			if rec.attempts() != test.attempts {
+				t.Errorf("got %d attempts, want %d", rec.attempts(), test.attempts)
+			}
+			letters, err := db.ListDeadLetters("owner")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(letters) != 1 {
+				t.Fatalf("got %d dead letters, want 1", len(letters))
+			}
+			letter := letters[0]
+			if letter.ID != "delivery" || letter.WebhookID != webhook.ID || letter.Attempts != test.attempts || letter.Payload != `{"type":"created"}` {
+				t.Errorf("got dead letter %+v", letter)
+			}
+		})
+	}
+}
+
+func TestDeliveryRefusesPrivateAddresses(t *testing.T) {
+	webhooks, db := newTestWebhooks(t)
+	rec := newReceiver(t, http.StatusOK)
+	webhook, err := webhooks.Create("owner", rec.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As if the host resolved to a public address when the webhook was created
+	webhooks.private = false
+
+	_, err = webhooks.post(context.Background(), delivery{id: "delivery", webhook: webhook, event: "created", payload: []byte("{}")})
+	if !errors.Is(err, errPrivateAddress) {
+		t.Errorf("got %v, want errPrivateAddress", err)
+	}
+	webhooks.deliver(context.Background(), delivery{id: "delivery", webhook: webhook, event: "created", payload: []byte("{}")})
+	if rec.attempts() != 0 {
+		t.Errorf("the receiver at %s was reached", rec.URL)
+	}
+	if letters, _ := db.ListDeadLetters("owner"); len(letters) != 1 || letters[0].Attempts != 1 {
+		t.Errorf("got dead letters %+v, want one given up after the first attempt", letters)
+	}
+}
+
+func TestRunWaitsForDeliveries(t *testing.T) {
+	webhooks, db := newTestWebhooks(t)
+	webhooks.retryDelay = time.Hour
+	rec := newReceiver(t, http.StatusServiceUnavailable)
+	if _, err := webhooks.Create("owner", rec.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := journal.NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		webhooks.Run(ctx, bus)
+		close(stopped)
+	}()
+	// Publish until Run has subscribed and the first attempt failed
+	for rec.attempts() == 0 {
+		bus.Publish(journal.Event{Type: journal.EntryCreated, Entry: models.Entry{ID: "id", Owner: "owner"}, Time: time.Now()})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after its deliveries gave up")
+	}
+	letters, err := db.ListDeadLetters("owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) == 0 {
+		t.Error("the deliveries given up on shutdown weren't recorded before Run returned")
+	}
+}
@@ -0,0 +1,183 @@
+// Package webhooks posts the changes to users' entries to URLs they
+// registered, such as chat bots or backup jobs.
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"journal/models"
+	"journal/pkg/api/v1"
+	"journal/pkg/auth"
+	"journal/pkg/journal"
+	"journal/pkg/storage"
+	"journal/pkg/utils"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// SecretPrefix starts every webhook secret so leaked secrets are easy to recognise
+const SecretPrefix = "whsec_"
+
+var (
+	// ErrInvalidURL is returned when creating a webhook without an http or https URL
+	ErrInvalidURL = v1.ErrInvalidWebhookURL
+	// ErrInvalidEvent is returned when creating a webhook for an unknown event type
+	ErrInvalidEvent = v1.ErrInvalidWebhookEvent
+	// ErrWebhookNotFound is returned for webhooks the user doesn't have
+	ErrWebhookNotFound = v1.ErrWebhookNotFound
+)
+
+// errPrivateAddress fails deliveries to an address refused by publicAddress,
+// such as a host name that resolved to a public address when the webhook was
+// created but to a private one since
+var errPrivateAddress = errors.New("webhooks can't be delivered to loopback, private or link-local addresses")
+
+// eventTypes are the event types a webhook can be sent
+var eventTypes = []string{string(journal.EntryCreated), string(journal.EntryUpdated), string(journal.EntryDeleted)}
+
+// Webhooks manages the webhooks of users and delivers events to them
+type Webhooks struct {
+	storage    storage.WebhookStorage
+	client     *http.Client
+	retryDelay time.Duration  // Wait before the first retry of a delivery
+	private    bool           // Whether webhooks may be sent to private addresses, for tests
+	deliveries sync.WaitGroup // Deliveries started by Run that haven't finished
+}
+
+// New returns webhooks kept in the given storage
+func New(store storage.WebhookStorage) *Webhooks {
+	webhooks := &Webhooks{storage: store, retryDelay: firstRetryDelay}
+	// Every address a delivery connects to is checked, including those of
+	// redirects and of host names resolving differently than at creation.
+	// Proxies aren't used, since the proxy's address would be checked instead.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: deliveryTimeout, Control: webhooks.control}).DialContext
+	webhooks.client = &http.Client{Timeout: deliveryTimeout, Transport: transport}
+	return webhooks
+}
+
+// publicAddress reports whether webhooks may be sent to the address. Loopback,
+// private, link-local (such as the cloud metadata service at 169.254.169.254)
+// and unspecified addresses are refused, so webhooks can't reach the server
+// itself or the network it runs in.
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsUnspecified() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast()
+}
+
+// control refuses connections to addresses that aren't public, once the host
+// name of the delivery has been resolved
+func (webhooks *Webhooks) control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !webhooks.private && !publicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// checkHost returns ErrInvalidURL when the host is, or resolves to, an
+// address webhooks may not be sent to
+func (webhooks *Webhooks) checkHost(host string) error {
+	if webhooks.private {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return fmt.Errorf("%w: %s doesn't resolve", ErrInvalidURL, host)
+	}
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return fmt.Errorf("%w: %s is %s", ErrInvalidURL, host, addr)
+		}
+	}
+	return nil
+}
+
+// Create registers a webhook sent the events of the given types, or of every
+// type if there are none. The secret deliveries are signed with is generated.
+// URLs whose host is or resolves to a loopback, private or link-local address
+// are refused.
+func (webhooks *Webhooks) Create(owner, rawURL string, events []string) (models.Webhook, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return models.Webhook{}, ErrInvalidURL
+	}
+	if err := webhooks.checkHost(parsed.Hostname()); err != nil {
+		return models.Webhook{}, err
+	}
+	for _, event := range events {
+		if !slices.Contains(eventTypes, event) {
+			return models.Webhook{}, ErrInvalidEvent
+		}
+	}
+
+	secret, err := auth.NewToken()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	webhook := models.Webhook{
+		ID:      utils.GenerateID(),
+		Owner:   owner,
+		URL:     parsed.String(),
+		Events:  events,
+		Secret:  SecretPrefix + secret,
+		Created: time.Now(),
+	}
+	if err := webhooks.storage.CreateWebhook(webhook); err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// List returns the webhooks of a user, oldest first.
+func (webhooks *Webhooks) List(owner string) ([]models.Webhook, error) {
+	return webhooks.storage.ListWebhooks(owner)
+}
+
+// Get returns one of the user's webhooks.
+func (webhooks *Webhooks) Get(owner, id string) (models.Webhook, error) {
+	webhook, err := webhooks.storage.GetWebhook(owner, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return webhook, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// Delete removes one of the user's webhooks. Deliveries already under way
+// still finish.
+func (webhooks *Webhooks) Delete(owner, id string) error {
+	err := webhooks.storage.DeleteWebhook(owner, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+// DeadLetters returns the deliveries to the user's webhooks that failed
+// every attempt, newest first.
+func (webhooks *Webhooks) DeadLetters(owner string) ([]models.DeadLetter, error) {
+	return webhooks.storage.ListDeadLetters(owner)
+}
+
+// wants reports whether the webhook is sent events of the type
+func wants(webhook models.Webhook, eventType journal.EventType) bool {
+	return len(webhook.Events) == 0 || slices.Contains(webhook.Events, string(eventType))
+}
@@ -0,0 +1,47 @@
+package webhooks
+
+import (
+	"errors"
+	"journal/pkg/storage"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestWebhooks returns webhooks kept in a fresh SQLite database, allowed
+// to reach httptest receivers and retrying quickly
+func newTestWebhooks(t *testing.T) (*Webhooks, *storage.SQLiteStorage) {
+	t.Helper()
+	db, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	webhooks := New(db)
+	webhooks.private = true
+	webhooks.retryDelay = time.Millisecond
+	return webhooks, db
+}
+
+func TestCreateRefusesPrivateAddresses(t *testing.T) {
+	webhooks, _ := newTestWebhooks(t)
+	webhooks.private = false
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+	} {
+		if _, err := webhooks.Create("owner", url, nil); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("%s: got %v, want ErrInvalidURL", url, err)
+		}
+	}
+
+	if _, err := webhooks.Create("owner", "https://93.184.215.14/hook", nil); err != nil {
+		t.Errorf("a public address was refused: %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+// Package templates embeds the HTML templates served by the web app so the
+// server binary can run from any working directory.
+package templates
+
+import "embed"
+
+// FS holds the layouts, pages and partials, with paths relative to this directory.
+//
+//go:embed layouts pages partials
+var FS embed.FS